@@ -0,0 +1,97 @@
+package paza
+
+// Action computes a value for a match from its node, its text,
+// and values of the nearest sub nodes having one, in order.
+// A non-nil error aborts the parse.
+// Actions may run on matches discarded later by backtracking or left recursion growth.
+type Action func(node *Node, text []byte, values []interface{}) (interface{}, error)
+
+// Action wraps a parser to compute a value when it matches.
+func (s *Set) Action(parser interface{}, action Action) *Pattern {
+	var fn Parser
+	var expr Expr
+	if p, ok := parser.(*Pattern); ok {
+		fn = p.Parser
+		expr = p.Expr
+	} else if p, ok := parser.(Parser); ok {
+		fn = p
+		expr = &CustomExpr{}
+	} else {
+		name := s.getNames(parser)[0]
+		fn = func(input *Input, start int) (bool, int, *Node) {
+			ok, l, node := s.Call(name, input, start)
+			if !ok {
+				return false, 0, nil
+			}
+			return true, l, &Node{
+				Start: start,
+				Len:   l,
+				Subs:  []*Node{node},
+			}
+		}
+		expr = s.nameExpr(name)
+	}
+	return s.record(&ActionExpr{Expr: expr}, func(input *Input, start int) (bool, int, *Node) {
+		// the text is passed to the action
+		input.mark(start)
+		ok, l, node := fn(input, start)
+		input.unmark()
+		input.cover(expr, ok)
+		if !ok {
+			return false, 0, nil
+		}
+		if node == nil {
+			node = &Node{
+				Start: start,
+				Len:   l,
+			}
+		}
+		value, err := action(node, input.Slice(start, start+l), input.subValues(node, nil))
+		if err != nil {
+			input.abort(err)
+			return false, 0, nil
+		}
+		if input.values == nil {
+			input.values = make(map[*Node]interface{})
+		}
+		input.values[node] = value
+		return true, l, node
+	})
+}
+
+func (s *Set) NamedAction(name string, parser interface{}, action Action) string {
+	s.Add(name, s.Action(parser, action))
+	return name
+}
+
+func (i *Input) subValues(node *Node, values []interface{}) []interface{} {
+	for _, sub := range node.Subs {
+		if value, ok := i.values[sub]; ok {
+			values = append(values, value)
+			continue
+		}
+		values = i.subValues(sub, values)
+	}
+	return values
+}
+
+// Value returns the value computed by actions for the node.
+// For a node without its own value, the value of the only nearest sub node having one is returned.
+func (i *Input) Value(node *Node) interface{} {
+	if value, ok := i.values[node]; ok {
+		return value
+	}
+	if values := i.subValues(node, nil); len(values) == 1 {
+		return values[0]
+	}
+	return nil
+}
+
+// ParseValue is like Parse but returns the value of the matched node.
+func (s *Set) ParseValue(name string, input *Input) (interface{}, error) {
+	node, err := s.Parse(name, input)
+	if node == nil {
+		return nil, err
+	}
+	return input.Value(node), err
+}
@@ -0,0 +1,126 @@
+package paza
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func actionCalcSet() *Set {
+	set := NewSet()
+	binary := func(op func(a, b int) (int, error)) Action {
+		return func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+			return op(values[0].(int), values[1].(int))
+		}
+	}
+	set.Add("expr", set.OrdChoice(
+		set.Action(set.Concat("expr", set.Rune('+'), "term"), binary(func(a, b int) (int, error) {
+			return a + b, nil
+		})),
+		set.Action(set.Concat("expr", set.Rune('-'), "term"), binary(func(a, b int) (int, error) {
+			return a - b, nil
+		})),
+		"term",
+	))
+	set.Add("term", set.OrdChoice(
+		set.Action(set.Concat("term", set.Rune('*'), "factor"), binary(func(a, b int) (int, error) {
+			return a * b, nil
+		})),
+		set.Action(set.Concat("term", set.Rune('/'), "factor"), binary(func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		})),
+		"factor",
+	))
+	set.Add("factor", set.OrdChoice(
+		set.Action(set.Regex(`[0-9]+`), func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+			return strconv.Atoi(string(text))
+		}),
+		set.Concat(set.Rune('('), "expr", set.Rune(')')),
+	))
+	return set
+}
+
+func TestAction(t *testing.T) {
+	set := actionCalcSet()
+	cases := []struct {
+		text  string
+		value int
+	}{
+		{"1", 1},
+		{"42", 42},
+		{"1+2", 3},
+		{"1-2-3", -4},
+		{"2*3+4", 10},
+		{"2+3*4", 14},
+		{"(2+3)*4", 20},
+		{"8/2/2", 2},
+		{"(1+2)*3-4/2", 7},
+		{"((((7))))", 7},
+	}
+	for _, memo := range []bool{false, true} {
+		for _, c := range cases {
+			input := NewInput([]byte(c.text))
+			if memo {
+				input.EnableMemo(-1)
+			}
+			value, err := set.ParseValue("expr", input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != c.value {
+				t.Fatalf("%s: got %v", c.text, value)
+			}
+		}
+	}
+
+	_, err := set.ParseValue("expr", NewInput([]byte("1+2/(3-3)")))
+	if err == nil || err.Error() != "division by zero" {
+		t.Fatalf("got %v", err)
+	}
+	_, err = set.ParseValue("expr", NewInput([]byte("1+")))
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestActionNamed(t *testing.T) {
+	set := NewSet()
+	set.NamedRegex("word", `[a-z]+`)
+	set.NamedAction("upper", "word", func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+		return string(text) + "!", nil
+	})
+	set.NamedAction("list", set.OneOrMore(set.Concat("upper", set.Rune(' '))), func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+		return values, nil
+	})
+	set.NamedAction("peek", set.Predicate("word"), func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+		return len(node.Subs), nil
+	})
+	input := NewInput([]byte("foo bar "))
+	node, err := set.Parse("list", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := input.Value(node).([]interface{})
+	if len(values) != 2 || values[0] != "foo!" || values[1] != "bar!" {
+		t.Fatalf("got %v", values)
+	}
+	upper := node.Subs[0].Subs[0]
+	if upper.Name != "upper" || upper.Subs[0].Name != "word" || input.Value(upper) != "foo!" {
+		t.Fatal("bad upper")
+	}
+	if input.Value(upper.Subs[0]) != nil {
+		t.Fatal("word should have no value")
+	}
+
+	input = NewInput([]byte("foo"))
+	if ok, l, node := set.Call("peek", input, 0); !ok || l != 0 || input.Value(node) != 0 {
+		t.Fatal("bad peek")
+	}
+
+	if s := set.Expr("list").String(); s != "(upper ' ')+" {
+		t.Fatal(s)
+	}
+}
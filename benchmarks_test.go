@@ -34,3 +34,65 @@ func BenchmarkNonRecursive(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkRecursiveMemo(b *testing.B) {
+	set := NewSet()
+	set.Add("expr", set.OrdChoice(
+		set.Concat(
+			"expr",
+			set.Regex(`[\+\-\*/]`),
+			set.Regex(`[a-z]+`),
+		),
+		set.Regex(`[a-z]+`),
+	))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		input := NewInput([]byte("foo+bar-baz*qux/quux"))
+		input.EnableMemo(-1)
+		ok, _, _ := set.Call("expr", input, 0)
+		if !ok {
+			b.Fatal("fail")
+		}
+	}
+}
+
+func backtrackSet() *Set {
+	set := NewSet()
+	set.Add("expr", set.OrdChoice(
+		set.Concat("term", set.Rune('+'), "expr"),
+		set.Concat("term", set.Rune('-'), "expr"),
+		"term",
+	))
+	set.Add("term", set.OrdChoice(
+		set.Concat(set.Rune('('), "expr", set.Rune(')')),
+		set.Regex(`[0-9]+`),
+	))
+	return set
+}
+
+var backtrackText = []byte("((((((1))))))-((((((2))))))")
+
+func BenchmarkBacktrack(b *testing.B) {
+	set := backtrackSet()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		input := NewInput(backtrackText)
+		ok, l, _ := set.Call("expr", input, 0)
+		if !ok || l != len(backtrackText) {
+			b.Fatal("fail")
+		}
+	}
+}
+
+func BenchmarkBacktrackMemo(b *testing.B) {
+	set := backtrackSet()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		input := NewInput(backtrackText)
+		input.EnableMemo(-1)
+		ok, l, _ := set.Call("expr", input, 0)
+		if !ok || l != len(backtrackText) {
+			b.Fatal("fail")
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package paza
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type CheckError struct {
+	Rule string
+	Msg  string
+}
+
+func (e *CheckError) Error() string {
+	return e.Rule + ": " + e.Msg
+}
+
+type checker struct {
+	set  *Set
+	errs []*CheckError
+}
+
+// Check analyzes the grammar statically and reports undefined references,
+// rules unreachable from start rules, unbounded repetition of expressions matching empty input,
+// choice alternatives shadowed by an always succeeding one,
+// and left recursive rules without a base case.
+// The first added rule is the start rule if none is given.
+func (s *Set) Check(start ...string) []*CheckError {
+	c := &checker{
+		set: s,
+	}
+	rules := s.Rules()
+	if len(start) == 0 && len(rules) > 0 {
+		start = rules[:1]
+	}
+
+	nullableRules := c.fixpoint(nullable)
+	neverFailsRules := c.fixpoint(neverFails)
+	succeedsRules := c.fixpoint(succeeds)
+
+	reachable := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if reachable[name] {
+			return
+		}
+		reachable[name] = true
+		if e, ok := s.rules[name]; ok {
+			for _, ref := range Refs(e) {
+				visit(ref)
+			}
+		}
+	}
+	for _, name := range start {
+		if _, ok := s.parsers[name]; !ok {
+			c.errorf(name, "start rule not defined")
+			continue
+		}
+		visit(name)
+	}
+
+	for _, name := range rules {
+		e := s.rules[name]
+		if !reachable[name] {
+			c.errorf(name, "unreachable from %s", expectedString(start))
+		}
+		undefined := make(map[string]bool)
+		for _, ref := range Refs(e) {
+			if _, ok := s.parsers[ref]; !ok && !undefined[ref] {
+				undefined[ref] = true
+				c.errorf(name, "undefined rule: %s", ref)
+			}
+		}
+		WalkExpr(e, func(e Expr) {
+			switch e := e.(type) {
+			case *RepeatExpr:
+				if e.Max <= 0 && nullable(e.Expr, nullableRules) {
+					c.errorf(name, "repetition of empty matching expression loops forever: %s", e)
+				}
+			case *ChoiceExpr:
+				for _, alt := range e.Exprs[:len(e.Exprs)-1] {
+					if neverFails(alt, neverFailsRules) {
+						c.errorf(name, "alternatives after %s are unreachable, it always succeeds", alt)
+						break
+					}
+				}
+			}
+		})
+		if !succeedsRules[name] && c.leftRecursive(name, nullableRules) {
+			c.errorf(name, "left recursion without base case")
+		}
+	}
+	return c.errs
+}
+
+func (c *checker) errorf(rule string, format string, args ...interface{}) {
+	c.errs = append(c.errs, &CheckError{
+		Rule: rule,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// fixpoint computes the least fixpoint of a rule property
+func (c *checker) fixpoint(property func(e Expr, rules map[string]bool) bool) map[string]bool {
+	rules := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for name, e := range c.set.rules {
+			if !rules[name] && property(e, rules) {
+				rules[name] = true
+				changed = true
+			}
+		}
+	}
+	return rules
+}
+
+// nullable reports whether e may succeed without consuming input
+func nullable(e Expr, rules map[string]bool) bool {
+	switch e := e.(type) {
+	case *RefExpr:
+		return rules[e.Name]
+	case *ActionExpr:
+		return nullable(e.Expr, rules)
+	case *RecoverExpr:
+		return nullable(e.Expr, rules)
+	case *OperatorsExpr:
+		return nullable(e.Operand, rules)
+	case *ConcatExpr:
+		for _, sub := range e.Exprs {
+			if !nullable(sub, rules) {
+				return false
+			}
+		}
+		return true
+	case *ChoiceExpr:
+		for _, sub := range e.Exprs {
+			if nullable(sub, rules) {
+				return true
+			}
+		}
+		return false
+	case *RepeatExpr:
+		return e.Min == 0 || nullable(e.Expr, rules)
+	case *PredicateExpr:
+		return true
+	case *CutExpr:
+		return true
+	case *RegexExpr:
+		return regexp.MustCompile(e.Pattern).MatchString("")
+	case *LiteralExpr:
+		return e.Text == ""
+	}
+	return false
+}
+
+// neverFails reports whether e succeeds on any input
+func neverFails(e Expr, rules map[string]bool) bool {
+	switch e := e.(type) {
+	case *RefExpr:
+		return rules[e.Name]
+	case *ActionExpr:
+		return neverFails(e.Expr, rules)
+	case *RecoverExpr:
+		return neverFails(e.Expr, rules)
+	case *OperatorsExpr:
+		return neverFails(e.Operand, rules)
+	case *ConcatExpr:
+		for _, sub := range e.Exprs {
+			if !neverFails(sub, rules) {
+				return false
+			}
+		}
+		return true
+	case *ChoiceExpr:
+		for _, sub := range e.Exprs {
+			if neverFails(sub, rules) {
+				return true
+			}
+		}
+		return false
+	case *RepeatExpr:
+		return e.Min == 0 || neverFails(e.Expr, rules)
+	case *PredicateExpr:
+		return !e.Not && neverFails(e.Expr, rules)
+	case *CutExpr:
+		return true
+	case *LiteralExpr:
+		return e.Text == ""
+	}
+	return false
+}
+
+// succeeds reports whether e may succeed on some input
+func succeeds(e Expr, rules map[string]bool) bool {
+	switch e := e.(type) {
+	case *RefExpr:
+		return rules[e.Name]
+	case *ActionExpr:
+		return succeeds(e.Expr, rules)
+	case *OperatorsExpr:
+		return succeeds(e.Operand, rules)
+	case *ConcatExpr:
+		for _, sub := range e.Exprs {
+			if !succeeds(sub, rules) {
+				return false
+			}
+		}
+		return true
+	case *ChoiceExpr:
+		for _, sub := range e.Exprs {
+			if succeeds(sub, rules) {
+				return true
+			}
+		}
+		return false
+	case *RepeatExpr:
+		return e.Min == 0 || succeeds(e.Expr, rules)
+	case *PredicateExpr:
+		return e.Not || succeeds(e.Expr, rules)
+	}
+	return true
+}
+
+// leftRecursive reports whether the rule may call itself without consuming input
+func (c *checker) leftRecursive(name string, nullableRules map[string]bool) bool {
+	visited := make(map[string]bool)
+	var visit func(e Expr) bool
+	visit = func(e Expr) bool {
+		switch e := e.(type) {
+		case *RefExpr:
+			if e.Name == name {
+				return true
+			}
+			if visited[e.Name] {
+				return false
+			}
+			visited[e.Name] = true
+			if sub, ok := c.set.rules[e.Name]; ok {
+				return visit(sub)
+			}
+		case *ConcatExpr:
+			for _, sub := range e.Exprs {
+				if visit(sub) {
+					return true
+				}
+				if !nullable(sub, nullableRules) {
+					break
+				}
+			}
+		case *ChoiceExpr:
+			for _, sub := range e.Exprs {
+				if visit(sub) {
+					return true
+				}
+			}
+		case *RepeatExpr:
+			return visit(e.Expr)
+		case *PredicateExpr:
+			return visit(e.Expr)
+		case *ActionExpr:
+			return visit(e.Expr)
+		case *RecoverExpr:
+			return visit(e.Expr)
+		case *OperatorsExpr:
+			for _, level := range e.Levels {
+				for _, op := range level.Ops {
+					if op.Fixity == Prefix && visit(op.Expr) {
+						return true
+					}
+				}
+			}
+			return visit(e.Operand)
+		}
+		return false
+	}
+	return visit(c.set.rules[name])
+}
@@ -0,0 +1,61 @@
+package paza
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	set := calcSet()
+	if errs := set.Check("expr"); len(errs) > 0 {
+		t.Fatalf("%v", errs)
+	}
+
+	set = NewSet()
+	err := set.LoadGrammar([]byte(`
+start   <- list / item / foo
+list    <- (item ','?)* ws*
+item    <- word / 'y'? / 'x'
+word    <- ws? [a-z]+
+ws      <- ' '*
+foo     <- bar
+left    <- left 'a' / left 'b'
+left2   <- b 'x' / left2
+b       <- left2 'y'
+ok-left <- ok-left 'a' / 'a'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"start: alternatives after list are unreachable, it always succeeds",
+		"list: repetition of empty matching expression loops forever: (item ','?)*",
+		"list: repetition of empty matching expression loops forever: ws*",
+		"item: alternatives after 'y'? are unreachable, it always succeeds",
+		"foo: undefined rule: bar",
+		"left: unreachable from start",
+		"left: left recursion without base case",
+		"left2: unreachable from start",
+		"left2: left recursion without base case",
+		"b: unreachable from start",
+		"b: left recursion without base case",
+		"ok-left: unreachable from start",
+	}
+	errs := set.Check()
+	if len(errs) != len(expected) {
+		t.Fatalf("%v", errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Fatalf("got %s", err)
+		}
+	}
+
+	errs = set.Check("foo", "left", "ok-left", "none")
+	expected = []string{
+		"none: start rule not defined",
+		"start: unreachable from foo, left, ok-left or none",
+	}
+	for i, e := range expected {
+		if errs[i].Error() != e {
+			t.Fatalf("got %s", errs[i])
+		}
+	}
+}
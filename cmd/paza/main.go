@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/reusee/paza"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage:
+	paza gen [-o output.go] [-pkg name] grammar.peg
+	paza check [-start rule,...] grammar.peg
+`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = gen(os.Args[2:])
+	case "check":
+		err = check(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "paza: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func loadGrammar(path string) (*paza.Grammar, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := paza.ParseGrammar(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return g, nil
+}
+
+func gen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	output := flags.String("o", "", "output file, default to stdout")
+	pkg := flags.String("pkg", "main", "package name")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+	g, err := loadGrammar(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := paza.GenerateGo(buf, g, *pkg); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(*output, buf.Bytes(), 0644)
+}
+
+func check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	start := flags.String("start", "", "comma separated start rules, default to the first rule")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+	g, err := loadGrammar(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	set := paza.NewSet()
+	set.AddGrammar(g)
+	var starts []string
+	if *start != "" {
+		starts = strings.Split(*start, ",")
+	}
+	errs := set.Check(starts...)
+	for _, err := range errs {
+		fmt.Printf("%s: %v\n", flags.Arg(0), err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d problems found", len(errs))
+	}
+	return nil
+}
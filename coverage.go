@@ -0,0 +1,150 @@
+package paza
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+type CoverageCount struct {
+	Ok     int
+	Failed int
+}
+
+// Coverage records how often each rule and sub expression of a set succeeded and failed.
+// Set it on the inputs of the parses to measure.
+type Coverage struct {
+	set    *Set
+	counts map[Expr]*CoverageCount
+}
+
+func NewCoverage(set *Set) *Coverage {
+	return &Coverage{
+		set:    set,
+		counts: make(map[Expr]*CoverageCount),
+	}
+}
+
+func (i *Input) cover(e Expr, ok bool) {
+	if i.Coverage == nil {
+		return
+	}
+	count, found := i.Coverage.counts[e]
+	if !found {
+		count = new(CoverageCount)
+		i.Coverage.counts[e] = count
+	}
+	if ok {
+		count.Ok++
+	} else {
+		count.Failed++
+	}
+}
+
+// Count returns the counts of an expression of the set grammar.
+func (c *Coverage) Count(e Expr) CoverageCount {
+	if count, ok := c.counts[e]; ok {
+		return *count
+	}
+	return CoverageCount{}
+}
+
+// Percent returns the percentage of expressions of the set grammar that ever succeeded.
+func (c *Coverage) Percent() float64 {
+	total, covered := 0, 0
+	for _, rule := range c.set.Grammar().Rules {
+		WalkExpr(rule.Expr, func(e Expr) {
+			total++
+			if c.Count(e).Ok > 0 {
+				covered++
+			}
+		})
+	}
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+type coverageLine struct {
+	level int
+	text  string
+	count CoverageCount
+}
+
+func (c *Coverage) lines() (lines []coverageLine) {
+	var walk func(e Expr, text string, level int)
+	walk = func(e Expr, text string, level int) {
+		lines = append(lines, coverageLine{level, text, c.Count(e)})
+		for _, sub := range subExprs(e) {
+			walk(sub, sub.String(), level+1)
+		}
+	}
+	for _, rule := range c.set.Grammar().Rules {
+		walk(rule.Expr, rule.Name+" <- "+rule.Expr.String(), 0)
+	}
+	return
+}
+
+func (l coverageLine) status() string {
+	switch {
+	case l.count.Ok == 0 && l.count.Failed == 0:
+		return "never tried"
+	case l.count.Ok == 0:
+		return "never succeeded"
+	case l.count.Failed == 0:
+		return "never failed"
+	}
+	return ""
+}
+
+// Report writes the counts of all rules and sub expressions, marking the ones never tried or never succeeded.
+func (c *Coverage) Report(w io.Writer) error {
+	for _, line := range c.lines() {
+		status := line.status()
+		if status != "" {
+			status = ", " + status
+		}
+		if _, err := fmt.Fprintf(w, "%s%s: %d ok, %d failed%s\n", strings.Repeat("  ", line.level),
+			line.text, line.count.Ok, line.count.Failed, status); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "coverage: %.1f%%\n", c.Percent())
+	return err
+}
+
+// HTML writes the report as a html page highlighting the untested parts.
+func (c *Coverage) HTML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+.covered { color: #2a7d2a; }
+.partial { color: #a67c00; }
+.uncovered { color: #c00000; font-weight: bold; }
+.count { color: #888888; }
+</style>
+</head>
+<body>
+<pre>
+`)
+	for _, line := range c.lines() {
+		class := "covered"
+		switch line.status() {
+		case "never tried", "never succeeded":
+			class = "uncovered"
+		case "never failed":
+			class = "partial"
+		}
+		fmt.Fprintf(&b, "%s<span class=\"%s\" title=\"%s\">%s</span> <span class=\"count\">%d ok, %d failed</span>\n",
+			strings.Repeat("  ", line.level), class, line.status(), html.EscapeString(line.text),
+			line.count.Ok, line.count.Failed)
+	}
+	fmt.Fprintf(&b, "</pre>\n<p>coverage: %.1f%%</p>\n</body>\n</html>\n", c.Percent())
+	_, err := io.WriteString(w, b.String())
+	return err
+}
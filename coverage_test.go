@@ -0,0 +1,83 @@
+package paza
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	set := calcSet()
+	coverage := NewCoverage(set)
+	for _, text := range []string{"1+2", "3*4", "5-"} {
+		input := NewInput([]byte(text))
+		input.Coverage = coverage
+		set.Parse("expr", input)
+	}
+
+	alternatives := set.Expr("expr").(*ChoiceExpr).Exprs
+	if c := coverage.Count(alternatives[0]); c != (CoverageCount{1, 6}) {
+		t.Fatalf("got %v", c)
+	}
+	if c := coverage.Count(alternatives[1]); c != (CoverageCount{0, 6}) {
+		t.Fatalf("got %v", c)
+	}
+	if c := coverage.Count(set.Expr("div-op")); c != (CoverageCount{0, 7}) {
+		t.Fatalf("got %v", c)
+	}
+	if c := coverage.Count(set.Expr("right-quote")); c != (CoverageCount{}) {
+		t.Fatalf("got %v", c)
+	}
+	if p := coverage.Percent(); p < 60 || p > 61 {
+		t.Fatalf("got %f", p)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := coverage.Report(buf); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, line := range []string{
+		"expr <- plus-expr / minus-expr / term: 10 ok, 6 failed\n",
+		"  minus-expr: 0 ok, 6 failed, never succeeded\n",
+		"  term: 6 ok, 0 failed, never failed\n",
+		"right-quote <- ')': 0 ok, 0 failed, never tried\n",
+		"coverage: 60.5%\n",
+	} {
+		if !strings.Contains(report, line) {
+			t.Fatalf("%q not in report:\n%s", line, report)
+		}
+	}
+
+	buf.Reset()
+	if err := coverage.HTML(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<span class="uncovered" title="never succeeded">div-op &lt;- &#39;/&#39;</span>`) {
+		t.Fatalf("got %s", buf.String())
+	}
+}
+
+func TestCoverageAnyRune(t *testing.T) {
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(`a <- 'x' . / 'y' .`)); err != nil {
+		t.Fatal(err)
+	}
+	coverage := NewCoverage(set)
+	input := NewInput([]byte("xz"))
+	input.Coverage = coverage
+	if _, err := set.Parse("a", input); err != nil {
+		t.Fatal(err)
+	}
+	alternatives := set.Expr("a").(*ChoiceExpr).Exprs
+	first, second := alternatives[0].(*ConcatExpr).Exprs[1], alternatives[1].(*ConcatExpr).Exprs[1]
+	if first == second {
+		t.Fatal("expressions not distinct")
+	}
+	if c := coverage.Count(first); c != (CoverageCount{1, 0}) {
+		t.Fatalf("got %v", c)
+	}
+	if c := coverage.Count(second); c != (CoverageCount{}) {
+		t.Fatalf("got %v", c)
+	}
+}
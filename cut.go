@@ -0,0 +1,47 @@
+package paza
+
+// Cut matches the empty string and commits the enclosing Concat:
+// if a later part of it fails, the parse is aborted with a *ParseError at that point
+// instead of backtracking into other alternatives.
+// Stack and memo entries, and the text of reader inputs, before the earliest position
+// the parse can still backtrack to are released.
+func (s *Set) Cut() *Pattern {
+	return s.record(&CutExpr{}, func(input *Input, start int) (bool, int, *Node) {
+		input.cut = true
+		input.cuts++
+		// report failures from here
+		input.farthest = start
+		input.expected = input.expected[:0]
+		input.release(input.barrier(start))
+		return true, 0, nil
+	})
+}
+
+func (s *Set) NamedCut(name string) string {
+	s.Add(name, s.Cut())
+	return name
+}
+
+// barrier returns the earliest position the parse can backtrack to
+func (i *Input) barrier(pos int) int {
+	for _, mark := range i.marks {
+		if mark < pos {
+			pos = mark
+		}
+	}
+	// left recursive calls restart from their start when growing
+	for _, entry := range i.stack {
+		if entry.active && entry.used && entry.start < pos {
+			pos = entry.start
+		}
+	}
+	return pos
+}
+
+func (i *Input) mark(pos int) {
+	i.marks = append(i.marks, pos)
+}
+
+func (i *Input) unmark() {
+	i.marks = i.marks[:len(i.marks)-1]
+}
@@ -0,0 +1,141 @@
+package paza
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func cutSet(cut bool) *Set {
+	set := NewSet()
+	grammar := `
+file <- stmt*
+stmt <- 'if' ^ ' ' ident ';' / 'while' ^ ' ' ident ';' / ident ';'
+ident <- [a-z]+
+`
+	if !cut {
+		grammar = strings.Replace(grammar, "^ ", "", -1)
+	}
+	if err := set.LoadGrammar([]byte(grammar)); err != nil {
+		panic(err)
+	}
+	return set
+}
+
+func TestCut(t *testing.T) {
+	cases := []struct {
+		text   string
+		cutErr string
+		err    string
+	}{
+		{"if foo;", "", ""},
+		{"foo;if;", "parse error at offset 6: expected ' ' (in file > stmt)", ""},
+		{"foo;if 1;", "parse error at offset 7: expected [a-z] (in file > stmt > ident)",
+			"parse error at offset 7: expected [a-z] (in file > stmt > ident)"},
+		{"while;", "parse error at offset 5: expected ' ' (in file > stmt)", ""},
+		{"ifx;", "parse error at offset 2: expected ' ' (in file > stmt)", ""},
+	}
+	for _, c := range cases {
+		for _, cut := range []bool{true, false} {
+			node, err := cutSet(cut).Parse("file", NewInput([]byte(c.text)))
+			expected := c.err
+			if cut {
+				expected = c.cutErr
+			}
+			if expected == "" {
+				if err != nil {
+					t.Fatalf("%q: %v", c.text, err)
+				}
+				if node.Len != len(c.text) {
+					t.Fatalf("%q: bad node", c.text)
+				}
+				continue
+			}
+			if err == nil || err.Error() != expected {
+				t.Fatalf("%q %v: got %v", c.text, cut, err)
+			}
+		}
+	}
+}
+
+func TestCutScope(t *testing.T) {
+	set := NewSet()
+	// the cut only commits the inner sequence
+	set.Add("foo", set.OrdChoice(
+		set.Concat(set.OrdChoice(set.Concat(set.Rune('a'), set.Cut()), set.Rune('b')), set.Rune('c')),
+		set.Literal("ad"),
+	))
+	if _, err := set.Parse("foo", NewInput([]byte("ad"))); err != nil {
+		t.Fatal(err)
+	}
+	set.Add("bar", set.OrdChoice(
+		set.Concat(set.Rune('a'), set.Cut(), set.Rune('c')),
+		set.Literal("ad"),
+	))
+	if _, err := set.Parse("bar", NewInput([]byte("ad"))); err == nil {
+		t.Fatal("should fail")
+	}
+	if _, err := set.Parse("bar", NewInput([]byte("ac"))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCutMemo(t *testing.T) {
+	for _, memo := range []bool{false, true} {
+		set := NewSet()
+		set.NamedCut("cut")
+		set.Add("a", set.Concat(set.Rune('k'), "cut", set.Rune('q')))
+		set.Add("b", set.Concat(set.Rune('k'), "cut", set.Rune('r')))
+		set.Add("x", set.OrdChoice(set.Concat("a", set.Rune('z')), "b", set.Literal("kqy")))
+		input := NewInput([]byte("kqy"))
+		if memo {
+			input.EnableMemo(-1)
+		}
+		_, err := set.Parse("x", input)
+		if err == nil || err.Error() != "parse error at offset 1: expected 'r' (in x > b)" {
+			t.Fatalf("memo %v: got %v", memo, err)
+		}
+	}
+}
+
+func TestCutRelease(t *testing.T) {
+	text := []byte(strings.Repeat("if foo;while bar;baz;", 1000))
+	for _, cut := range []bool{true, false} {
+		set := cutSet(cut)
+		maxStack, maxMemo := 0, 0
+		set.Add("ident", set.Concat(set.Regex(`[a-z]+`), Parser(func(input *Input, start int) (bool, int, *Node) {
+			if len(input.stack) > maxStack {
+				maxStack = len(input.stack)
+			}
+			if len(input.memo) > maxMemo {
+				maxMemo = len(input.memo)
+			}
+			return true, 0, nil
+		})))
+		input := NewInput(text)
+		input.EnableMemo(-1)
+		if _, err := set.Parse("file", input); err != nil {
+			t.Fatal(err)
+		}
+		if maxStack > 20 {
+			t.Fatalf("stack not released: %d", maxStack)
+		}
+		if cut && maxMemo > 50 {
+			t.Fatalf("memo not released: %d", maxMemo)
+		}
+		if !cut && maxMemo < 3000 {
+			t.Fatalf("memo: %d", maxMemo)
+		}
+	}
+
+	// stream with a single top level call
+	input := NewReaderInput(bytes.NewReader(text))
+	input.EnableMemo(-1)
+	set := cutSet(true)
+	if _, err := set.Parse("file", input); err != nil {
+		t.Fatal(err)
+	}
+	if cap(input.Text) > 4*readChunk {
+		t.Fatalf("buffer not bounded: %d", cap(input.Text))
+	}
+}
@@ -0,0 +1,368 @@
+package paza
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// encodedNode is the serialized form of a Node.
+// Ends and texts are only used when encoding, Len is recomputed from End when decoding.
+type encodedNode struct {
+	XMLName   xml.Name       `json:"-"`
+	Name      string         `json:"name" xml:"name,attr,omitempty"`
+	Named     bool           `json:"named,omitempty" xml:"named,attr,omitempty"`
+	Path      string         `json:"path,omitempty" xml:"path,attr,omitempty"`
+	Start     int            `json:"start" xml:"start,attr,omitempty"`
+	End       int            `json:"end" xml:"end,attr,omitempty"`
+	Line      int            `json:"line,omitempty" xml:"line,attr,omitempty"`
+	Column    int            `json:"column,omitempty" xml:"column,attr,omitempty"`
+	EndLine   int            `json:"endLine,omitempty" xml:"end-line,attr,omitempty"`
+	EndColumn int            `json:"endColumn,omitempty" xml:"end-column,attr,omitempty"`
+	Text      string         `json:"text,omitempty" xml:",chardata"`
+	Subs      []*encodedNode `json:"subs,omitempty" xml:",any"`
+}
+
+// encode converts the tree, adding positions and leaf texts if input is not nil
+func encode(node *Node, input *Input, text bool) *encodedNode {
+	var positions map[int]Position
+	if input != nil {
+		positions = nodePositions(node, input)
+	}
+	var conv func(node *Node) *encodedNode
+	conv = func(node *Node) *encodedNode {
+		if node == nil {
+			return nil
+		}
+		e := &encodedNode{
+			Name:  node.Name,
+			Named: node.Named,
+			Path:  node.Path,
+			Start: node.Start,
+			End:   node.Start + node.Len,
+		}
+		if positions != nil {
+			start, end := positions[e.Start], positions[e.End]
+			e.Line, e.Column = start.Line, start.Column
+			e.EndLine, e.EndColumn = end.Line, end.Column
+			if text && len(node.Subs) == 0 {
+				e.Text = string(input.Slice(e.Start, e.End))
+			}
+		}
+		for _, sub := range node.Subs {
+			e.Subs = append(e.Subs, conv(sub))
+		}
+		return e
+	}
+	return conv(node)
+}
+
+func (e *encodedNode) decode() *Node {
+	if e == nil {
+		return nil
+	}
+	node := &Node{
+		Name:  e.Name,
+		Named: e.Named,
+		Path:  e.Path,
+		Start: e.Start,
+		Len:   e.End - e.Start,
+	}
+	for _, sub := range e.Subs {
+		node.Subs = append(node.Subs, sub.decode())
+	}
+	return node
+}
+
+// nodePositions computes positions of all node starts and ends in one pass over the text
+func nodePositions(node *Node, input *Input) map[int]Position {
+	var offsets []int
+	Walk(node, func(node *Node) error {
+		offsets = append(offsets, node.Start, node.Start+node.Len)
+		return nil
+	}, nil)
+	sort.Ints(offsets)
+	positions := make(map[int]Position, len(offsets))
+	var pos Position
+	last := -1
+	for _, offset := range offsets {
+		if offset == last {
+			continue
+		}
+		if last < 0 {
+			pos = input.Position(offset)
+		} else {
+			text := input.Slice(last, offset)
+			if lines := bytes.Count(text, []byte("\n")); lines > 0 {
+				pos.Line += lines
+				pos.Column = utf8.RuneCount(text[bytes.LastIndexByte(text, '\n')+1:]) + 1
+			} else {
+				pos.Column += utf8.RuneCount(text)
+			}
+		}
+		positions[offset] = pos
+		last = offset
+	}
+	return positions
+}
+
+// EncodeJSON writes the tree as JSON objects with name, named, path, start, end and subs fields.
+// If input is not nil, line, column, endLine and endColumn are added,
+// and if text is true, the matched text of nodes without sub nodes in text fields.
+func EncodeJSON(w io.Writer, node *Node, input *Input, text bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(encode(node, input, text))
+}
+
+// DecodeJSON reads a tree written by EncodeJSON.
+func DecodeJSON(r io.Reader) (*Node, error) {
+	var e *encodedNode
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return nil, err
+	}
+	return e.decode(), nil
+}
+
+// EncodeXML writes the tree as nested node elements, with attributes like the fields of EncodeJSON.
+// Zero attributes are omitted, texts are the character data of elements, nil sub nodes are written as nil elements.
+func EncodeXML(w io.Writer, node *Node, input *Input, text bool) error {
+	var mark func(e *encodedNode) *encodedNode
+	mark = func(e *encodedNode) *encodedNode {
+		if e == nil {
+			return &encodedNode{XMLName: xml.Name{Local: "nil"}}
+		}
+		e.XMLName = xml.Name{Local: "node"}
+		for i, sub := range e.Subs {
+			e.Subs[i] = mark(sub)
+		}
+		return e
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(mark(encode(node, input, text))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// DecodeXML reads a tree written by EncodeXML.
+func DecodeXML(r io.Reader) (*Node, error) {
+	var e encodedNode
+	if err := xml.NewDecoder(r).Decode(&e); err != nil {
+		return nil, err
+	}
+	var unmark func(e *encodedNode) *encodedNode
+	unmark = func(e *encodedNode) *encodedNode {
+		if e.XMLName.Local == "nil" {
+			return nil
+		}
+		for i, sub := range e.Subs {
+			e.Subs[i] = unmark(sub)
+		}
+		return e
+	}
+	return unmark(&e).decode(), nil
+}
+
+/*
+EncodeSExpr writes the tree as S-expressions, one node per line:
+
+	("expr" 0 3 1:1 1:4
+	  ("plus-expr" 0 3 1:1 1:4
+	    ...))
+
+Names of named nodes are quoted, names of anonymous nodes are not,
+unless they are empty or have spaces, parentheses or quotes, then they are quoted after a #.
+The quoted path of anonymous nodes follows if any, then the start and end offsets, then the start and end positions if input is not nil,
+then the quoted text of nodes without sub nodes if text is true, then sub nodes.
+nil sub nodes are written as nil.
+*/
+func EncodeSExpr(w io.Writer, node *Node, input *Input, text bool) error {
+	bw := bufio.NewWriter(w)
+	var write func(e *encodedNode, level int)
+	write = func(e *encodedNode, level int) {
+		if e == nil {
+			bw.WriteString("nil")
+			return
+		}
+		bw.WriteString("(")
+		if e.Named {
+			bw.WriteString(strconv.Quote(e.Name))
+		} else if e.Name == "" || strings.ContainsAny(e.Name, " \t\r\n()\"") {
+			bw.WriteString("#" + strconv.Quote(e.Name))
+		} else {
+			bw.WriteString(e.Name)
+		}
+		if e.Path != "" {
+			bw.WriteString(" " + strconv.Quote(e.Path))
+		}
+		fmt.Fprintf(bw, " %d %d", e.Start, e.End)
+		if e.Line > 0 {
+			fmt.Fprintf(bw, " %d:%d %d:%d", e.Line, e.Column, e.EndLine, e.EndColumn)
+		}
+		if e.Text != "" {
+			bw.WriteString(" " + strconv.Quote(e.Text))
+		}
+		for _, sub := range e.Subs {
+			bw.WriteString("\n" + strings.Repeat("  ", level+1))
+			write(sub, level+1)
+		}
+		bw.WriteString(")")
+	}
+	write(encode(node, input, text), 0)
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// DecodeSExpr reads a tree written by EncodeSExpr.
+func DecodeSExpr(r io.Reader) (*Node, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &sexprParser{src: src}
+	node, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	return node, nil
+}
+
+type sexprParser struct {
+	src []byte
+	pos int
+}
+
+func (p *sexprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("sexpr offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *sexprParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// atom reads a quoted string, a quoted string after a # as a bare word, or a bare word
+func (p *sexprParser) atom() (string, bool, error) {
+	p.skipSpace()
+	if bytes.HasPrefix(p.src[p.pos:], []byte(`#"`)) {
+		p.pos++
+		s, _, err := p.atom()
+		return s, false, err
+	}
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		for p.pos++; p.pos < len(p.src) && p.src[p.pos] != '"'; p.pos++ {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		if p.pos >= len(p.src) {
+			return "", false, p.errorf("unterminated string")
+		}
+		p.pos++
+		s, err := strconv.Unquote(string(p.src[start:p.pos]))
+		if err != nil {
+			p.pos = start
+			return "", false, p.errorf("bad string")
+		}
+		return s, true, nil
+	}
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n()\"", p.src[p.pos]) < 0 {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", false, p.errorf("expected atom")
+	}
+	return string(p.src[start:p.pos]), false, nil
+}
+
+func (p *sexprParser) int() (int, error) {
+	start := p.pos
+	s, quoted, err := p.atom()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if quoted || err != nil {
+		p.pos = start
+		p.skipSpace()
+		return 0, p.errorf("expected offset")
+	}
+	return n, nil
+}
+
+func (p *sexprParser) node() (*Node, error) {
+	p.skipSpace()
+	if bytes.HasPrefix(p.src[p.pos:], []byte("nil")) {
+		p.pos += 3
+		return nil, nil
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+		return nil, p.errorf("expected node")
+	}
+	p.pos++
+	name, named, err := p.atom()
+	if err != nil {
+		return nil, err
+	}
+	var path string
+	if p.skipSpace(); p.pos < len(p.src) && p.src[p.pos] == '"' {
+		if path, _, err = p.atom(); err != nil {
+			return nil, err
+		}
+	}
+	start, err := p.int()
+	if err != nil {
+		return nil, err
+	}
+	end, err := p.int()
+	if err != nil {
+		return nil, err
+	}
+	node := &Node{
+		Name:  name,
+		Named: named,
+		Path:  path,
+		Start: start,
+		Len:   end - start,
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated node")
+		}
+		switch c := p.src[p.pos]; {
+		case c == ')':
+			p.pos++
+			return node, nil
+		case c == '(' || bytes.HasPrefix(p.src[p.pos:], []byte("nil")):
+			sub, err := p.node()
+			if err != nil {
+				return nil, err
+			}
+			node.Subs = append(node.Subs, sub)
+		case len(node.Subs) > 0:
+			return nil, p.errorf("expected node")
+		default:
+			// positions and text are not decoded
+			if _, _, err := p.atom(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package paza
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	set := calcSet()
+	set.Add("stmt", set.Concat("expr", set.NotPredicate(set.Rune('+')), set.Regex(`\n*`)))
+	input := NewInput([]byte("1+(2*3)\n"))
+	node, err := set.Parse("stmt", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formats := []struct {
+		name   string
+		encode func(io.Writer, *Node, *Input, bool) error
+		decode func(io.Reader) (*Node, error)
+	}{
+		{"json", EncodeJSON, DecodeJSON},
+		{"sexpr", EncodeSExpr, DecodeSExpr},
+		{"xml", EncodeXML, DecodeXML},
+	}
+	for _, format := range formats {
+		for _, text := range []bool{false, true} {
+			for _, in := range []*Input{nil, input} {
+				buf := new(bytes.Buffer)
+				if err := format.encode(buf, node, in, text); err != nil {
+					t.Fatal(err)
+				}
+				decoded, err := format.decode(buf)
+				if err != nil {
+					t.Fatalf("%s: %v", format.name, err)
+				}
+				if !decoded.Equal(node) {
+					t.Fatalf("%s: not equal", format.name)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeSExpr(t *testing.T) {
+	set := NewSet()
+	set.Add("pair", set.Concat(set.NamedRegex("key", `[a-z]+`), set.Literal("\n= "), "key"))
+	input := NewInput([]byte("a\n= bc"))
+	node, err := set.Parse("pair", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := EncodeSExpr(buf, node, input, true); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != `("pair" 0 6 1:1 2:5
+  ("key" 0 1 1:1 1:2 "a")
+  (literal "pair/1" 1 4 1:2 2:3 "\n= ")
+  ("key" 4 6 2:3 2:5 "bc"))
+` {
+		t.Fatalf("got %s", s)
+	}
+
+	// anonymous names that are not bare words
+	node = &Node{Name: "", Start: 0, Len: 3, Subs: []*Node{
+		{Name: "a b", Start: 0, Len: 1},
+		{Name: "(x)", Start: 1, Len: 1},
+		{Name: `"y"`, Start: 2, Len: 1, Named: true},
+	}}
+	buf.Reset()
+	if err := EncodeSExpr(buf, node, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != `(#"" 0 3
+  (#"a b" 0 1)
+  (#"(x)" 1 2)
+  ("\"y\"" 2 3))
+` {
+		t.Fatalf("got %s", s)
+	}
+	decoded, err := DecodeSExpr(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(node) {
+		t.Fatal("not equal")
+	}
+
+	for _, c := range []struct {
+		text string
+		err  string
+	}{
+		{`("a" 0`, "sexpr offset 6: expected atom"},
+		{`(#"a 0 1)`, "sexpr offset 9: unterminated string"},
+		{`("a" 0 x)`, "sexpr offset 7: expected offset"},
+		{`("a" 0 1 ("b" 0 1) "c")`, "sexpr offset 19: expected node"},
+		{`("a" 0 1`, "sexpr offset 8: unterminated node"},
+		{`("a" 0 1) x`, "sexpr offset 10: unexpected 'x'"},
+		{`"a"`, "sexpr offset 0: expected node"},
+	} {
+		_, err := DecodeSExpr(strings.NewReader(c.text))
+		if err == nil || err.Error() != c.err {
+			t.Fatalf("%s: got %v", c.text, err)
+		}
+	}
+}
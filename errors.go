@@ -0,0 +1,206 @@
+package paza
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ParseError struct {
+	Offset   int
+	Expected []string
+	Stack    []string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error at offset %d%s", e.Offset, e.message())
+}
+
+func (e *ParseError) message() string {
+	msg := ""
+	if len(e.Expected) > 0 {
+		msg += ": expected " + expectedString(e.Expected)
+	}
+	if len(e.Stack) > 0 {
+		msg += " (in " + strings.Join(e.Stack, " > ") + ")"
+	}
+	return msg
+}
+
+// Report writes the error with its line and column, the source line and a caret under the failure point.
+func (e *ParseError) Report(writer io.Writer, input *Input) {
+	pos := input.Position(e.Offset)
+	fmt.Fprintf(writer, "%d:%d%s\n", pos.Line, pos.Column, e.message())
+	input.fill(e.Offset)
+	offset := e.Offset - input.base
+	lineStart := bytes.LastIndexByte(input.Text[:offset], '\n') + 1
+	lineEnd := bytes.IndexByte(input.Text[offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(input.Text)
+	} else {
+		lineEnd += offset
+	}
+	line := bytes.TrimRight(input.Text[lineStart:lineEnd], "\r")
+	fmt.Fprintf(writer, "%s\n", line)
+	// keep tabs so the caret lines up
+	caret := make([]byte, 0, pos.Column)
+	for _, r := range string(input.Text[lineStart:offset]) {
+		if r == '\t' {
+			caret = append(caret, '\t')
+		} else {
+			caret = append(caret, ' ')
+		}
+	}
+	fmt.Fprintf(writer, "%s^\n", caret)
+}
+
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Position converts a byte offset to a 1-based line and rune column.
+func (i *Input) Position(offset int) Position {
+	text := i.Slice(i.base, offset)
+	lineStart := bytes.LastIndexByte(text, '\n') + 1
+	pos := Position{
+		Line:   i.lines + bytes.Count(text, []byte("\n")) + 1,
+		Column: utf8.RuneCount(text[lineStart:]) + 1,
+	}
+	if lineStart == 0 { // line started in discarded text
+		pos.Column += i.column
+	}
+	return pos
+}
+
+func expectedString(expected []string) string {
+	if len(expected) == 1 {
+		return expected[0]
+	}
+	return strings.Join(expected[:len(expected)-1], ", ") + " or " + expected[len(expected)-1]
+}
+
+func byteClass(bs []byte) string {
+	quoted := strconv.Quote(string(bs))
+	return "[" + strings.Replace(quoted[1:len(quoted)-1], "]", `\]`, -1) + "]"
+}
+
+type failState struct {
+	farthest  int
+	expected  []string
+	failStack []string
+}
+
+// saveFail starts recording failures from start afresh, returning the previous ones
+func (i *Input) saveFail(start int) failState {
+	saved := i.failState
+	i.failState = failState{farthest: start}
+	return saved
+}
+
+// restoreFail merges failures saved by saveFail with the ones recorded since
+func (i *Input) restoreFail(saved failState) {
+	if len(i.expected) == 0 || len(saved.expected) > 0 && saved.farthest > i.farthest {
+		i.failState = saved
+		return
+	}
+	if len(saved.expected) == 0 || saved.farthest < i.farthest {
+		return
+	}
+	expected := i.expected
+	i.failState = saved
+	for _, e := range expected {
+		i.fail(i.farthest, e)
+	}
+}
+
+// ownFail returns a copy of failures recorded since saveFail by a call at depth
+func (i *Input) ownFail(depth int) failState {
+	if len(i.expected) == 0 {
+		return failState{}
+	}
+	return failState{
+		farthest:  i.farthest,
+		expected:  append([]string(nil), i.expected...),
+		failStack: append([]string(nil), i.failStack[depth:]...),
+	}
+}
+
+// replayFail records failures returned by ownFail for a call at the current depth
+func (i *Input) replayFail(f failState) {
+	if len(f.expected) == 0 || f.farthest < i.farthest {
+		return
+	}
+	if f.farthest > i.farthest || len(i.expected) == 0 {
+		i.farthest = f.farthest
+		i.expected = append(i.expected[:0], f.expected...)
+		i.failStack = append(append(i.failStack[:0], i.calls...), f.failStack...)
+		return
+	}
+	for _, e := range f.expected {
+		i.fail(f.farthest, e)
+	}
+}
+
+// fail records a terminal failing at pos, keeping the farthest ones.
+func (i *Input) fail(pos int, expected string) {
+	if pos < i.farthest {
+		return
+	}
+	if pos > i.farthest || len(i.expected) == 0 {
+		i.farthest = pos
+		i.expected = i.expected[:0]
+		i.failStack = append(i.failStack[:0], i.calls...)
+	}
+	for _, e := range i.expected {
+		if e == expected {
+			return
+		}
+	}
+	i.expected = append(i.expected, expected)
+}
+
+func (i *Input) parseError() *ParseError {
+	err := &ParseError{
+		Offset:   i.farthest,
+		Expected: append([]string(nil), i.expected...),
+	}
+	for _, name := range i.failStack {
+		if isAnonymous(name) {
+			continue
+		}
+		err.Stack = append(err.Stack, name)
+	}
+	return err
+}
+
+// Parse matches the whole input with the named parser.
+// On failure the returned error is a *ParseError at the farthest position any terminal failed,
+// or the error aborting the parse.
+// If the input was matched with errors recovered by Recover, the node is returned with an ErrorList.
+// Failures, the aborting error and limit counters of an earlier Parse on the input are cleared,
+// memoized results are kept.
+func (s *Set) Parse(name string, input *Input) (*Node, error) {
+	input.failState = failState{}
+	input.err = nil
+	input.numCalls = 0
+	input.cut = false
+	ok, l, node := s.Call(name, input, 0)
+	end := ok && input.atEnd(l)
+	if input.err != nil {
+		return nil, input.err
+	}
+	if end {
+		if errs := input.Errors(node); len(errs) > 0 {
+			return node, errs
+		}
+		return node, nil
+	}
+	if ok {
+		input.fail(l, "end of input")
+	}
+	return nil, input.parseError()
+}
@@ -0,0 +1,153 @@
+package paza
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	set := calcSet()
+	cases := []struct {
+		text     string
+		offset   int
+		expected []string
+		err      string
+	}{
+		{"", 0, []string{"[0-9]+", "'('"},
+			"parse error at offset 0: expected [0-9]+ or '(' (in expr > term > factor > digit)"},
+		{"1+", 2, []string{"[0-9]+", "'('"},
+			"parse error at offset 2: expected [0-9]+ or '(' (in expr > plus-expr > term > factor > digit)"},
+		{"1)", 1, []string{"'*'", "'/'", "'+'", "'-'", "end of input"},
+			"parse error at offset 1: expected '*', '/', '+', '-' or end of input (in expr > term > mul-expr > mul-op)"},
+		{"(1+2", 4, []string{"'*'", "'/'", "'+'", "'-'", "')'"},
+			"parse error at offset 4: expected '*', '/', '+', '-' or ')' (in expr > term > factor > quoted > expr > plus-expr > term > mul-expr > mul-op)"},
+	}
+	for _, c := range cases {
+		node, err := set.Parse("expr", NewInput([]byte(c.text)))
+		if node != nil {
+			t.Fatalf("%q: should fail", c.text)
+		}
+		e, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("%q: %v", c.text, err)
+		}
+		if e.Offset != c.offset {
+			t.Fatalf("%q: offset %d", c.text, e.Offset)
+		}
+		if len(e.Expected) != len(c.expected) {
+			t.Fatalf("%q: expected %v", c.text, e.Expected)
+		}
+		for i, expected := range c.expected {
+			if e.Expected[i] != expected {
+				t.Fatalf("%q: expected %v", c.text, e.Expected)
+			}
+		}
+		if e.Error() != c.err {
+			t.Fatalf("%q: %s", c.text, e.Error())
+		}
+	}
+
+	node, err := set.Parse("expr", NewInput([]byte("(1+2)*3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Name != "expr" || node.Len != 7 {
+		t.Fatalf("bad node")
+	}
+}
+
+func TestParseTwice(t *testing.T) {
+	set := NewSet()
+	set.Add("a", set.Concat(set.Literal("xyz"), set.Rune('w')))
+	set.Add("b", set.Literal("xy"))
+	input := NewInput([]byte("xyzq"))
+	input.Limits.MaxCalls = 5
+	_, err := set.Parse("a", input)
+	if err == nil || err.Error() != "parse error at offset 3: expected 'w' (in a)" {
+		t.Fatalf("got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err = set.Parse("b", input)
+		if err == nil || err.Error() != "parse error at offset 2: expected end of input" {
+			t.Fatalf("got %v", err)
+		}
+	}
+}
+
+func TestParseErrorTerminals(t *testing.T) {
+	set := NewSet()
+	set.Add("foo", set.OrdChoice(
+		set.ByteIn([]byte("ab]")),
+		set.ByteRange('0', '9'),
+		set.Regex(`x+`),
+	))
+	_, err := set.Parse("foo", NewInput([]byte("?")))
+	if err.Error() != `parse error at offset 0: expected [ab\]], [0-9] or x+ (in foo)` {
+		t.Fatal(err)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	input := NewInput([]byte("ab\n白c\n\nd"))
+	cases := []struct {
+		offset int
+		pos    Position
+	}{
+		{0, Position{1, 1}},
+		{1, Position{1, 2}},
+		{2, Position{1, 3}},
+		{3, Position{2, 1}},
+		{6, Position{2, 2}},
+		{7, Position{2, 3}},
+		{8, Position{3, 1}},
+		{9, Position{4, 1}},
+		{10, Position{4, 2}},
+	}
+	for _, c := range cases {
+		if pos := input.Position(c.offset); pos != c.pos {
+			t.Fatalf("%d: %v", c.offset, pos)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	set := NewSet()
+	set.Add("expr", set.OrdChoice(
+		set.Concat("expr", set.Rune('+'), "num"),
+		"num",
+	))
+	set.Add("num", set.Concat(
+		set.ZeroOrMore(set.ByteIn([]byte(" \t\n"))),
+		set.Regex(`[0-9]+`),
+	))
+	cases := []struct {
+		text   string
+		report string
+	}{
+		{"1+", `1:3: expected [ \t\n] or [0-9]+ (in expr > num)
+1+
+  ^
+`},
+		{"1+2+\n\t+3", `2:2: expected [ \t\n] or [0-9]+ (in expr > num)
+	+3
+	^
+`},
+		{"1+\n 白", `2:2: expected [ \t\n] or [0-9]+ (in expr > num)
+ 白
+ ^
+`},
+		{"1+ 2\r\n", `1:5: expected '+' or end of input (in expr)
+1+ 2
+    ^
+`},
+	}
+	for _, c := range cases {
+		input := NewInput([]byte(c.text))
+		_, err := set.Parse("expr", input)
+		buf := new(bytes.Buffer)
+		err.(*ParseError).Report(buf, input)
+		if buf.String() != c.report {
+			t.Fatalf("%q: got\n%s", c.text, buf.String())
+		}
+	}
+}
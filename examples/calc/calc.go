@@ -0,0 +1,578 @@
+// Code generated by paza gen. DO NOT EDIT.
+
+package calc
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/reusee/paza"
+)
+
+// Call matches the named rule at start, like paza.Set.Call.
+func Call(name string, text []byte, start int) (bool, int, *paza.Node) {
+	p := &parser{
+		text: text,
+	}
+	switch name {
+	case "expr":
+		return p.call(0, start)
+	case "plus-expr":
+		return p.call(2, start)
+	case "minus-expr":
+		return p.call(4, start)
+	case "term":
+		return p.call(9, start)
+	case "factor":
+		return p.call(12, start)
+	case "sign":
+		return p.call(14, start)
+	case "digit":
+		return p.call(17, start)
+	case "quoted":
+		return p.call(20, start)
+	}
+	panic("parser not found: " + name)
+}
+
+type stackEntry struct {
+	rule   int
+	start  int
+	ok     bool
+	length int
+	node   *paza.Node
+	used   bool
+}
+
+type parser struct {
+	text  []byte
+	stack []stackEntry
+}
+
+func (p *parser) call(rule int, start int) (bool, int, *paza.Node) {
+	// search stack
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		mem := p.stack[i]
+		if mem.rule == rule && mem.start == start {
+			p.stack[i].used = true
+			return mem.ok, mem.length, named(mem.node, rule)
+		}
+	}
+	index := len(p.stack)
+	p.stack = append(p.stack, stackEntry{
+		rule:  rule,
+		start: start,
+	})
+	// pop the entry, a later call must not see a result of an unfinished growth
+	defer func() {
+		p.stack = p.stack[:index]
+	}()
+	// find the right bound
+	lastOk := false
+	lastLen := 0
+	var lastNode *paza.Node
+	for {
+		ok, l, node := p.dispatch(rule, start)
+		p.stack = p.stack[:index+1]
+		if !ok {
+			return false, 0, nil
+		}
+		if !p.stack[index].used {
+			return ok, l, named(node, rule)
+		}
+		if l < lastLen {
+			return lastOk, lastLen, named(lastNode, rule)
+		} else if l == lastLen {
+			return ok, l, named(node, rule)
+		}
+		lastOk = ok
+		lastLen = l
+		lastNode = node
+		p.stack[index].ok = ok
+		p.stack[index].length = l
+		p.stack[index].node = node
+	}
+}
+
+func named(node *paza.Node, rule int) *paza.Node {
+	if node != nil {
+		node.Name = ruleNames[rule]
+		node.Named = namedRules[rule]
+		node.Path = rulePaths[rule]
+	}
+	return node
+}
+
+func (p *parser) dispatch(rule int, start int) (bool, int, *paza.Node) {
+	switch rule {
+	case 0:
+		return p.rule0(start)
+	case 1:
+		return p.rule1(start)
+	case 2:
+		return p.rule2(start)
+	case 3:
+		return p.rule3(start)
+	case 4:
+		return p.rule4(start)
+	case 5:
+		return p.rule5(start)
+	case 6:
+		return p.rule6(start)
+	case 7:
+		return p.rule7(start)
+	case 8:
+		return p.rule8(start)
+	case 9:
+		return p.rule9(start)
+	case 10:
+		return p.rule10(start)
+	case 11:
+		return p.rule11(start)
+	case 12:
+		return p.rule12(start)
+	case 13:
+		return p.rule13(start)
+	case 14:
+		return p.rule14(start)
+	case 15:
+		return p.rule15(start)
+	case 16:
+		return p.rule16(start)
+	case 17:
+		return p.rule17(start)
+	case 18:
+		return p.rule18(start)
+	case 19:
+		return p.rule19(start)
+	case 20:
+		return p.rule20(start)
+	}
+	panic("bad rule")
+}
+
+var ruleNames = [...]string{
+	"expr",
+	"rune",
+	"plus-expr",
+	"rune",
+	"minus-expr",
+	"rune",
+	"rune",
+	"concat",
+	"concat",
+	"term",
+	"concat",
+	"concat",
+	"factor",
+	"rune",
+	"sign",
+	"regex",
+	"repeat",
+	"digit",
+	"rune",
+	"rune",
+	"quoted",
+}
+
+var namedRules = [...]bool{
+	true,
+	false,
+	true,
+	false,
+	true,
+	false,
+	false,
+	false,
+	false,
+	true,
+	false,
+	false,
+	true,
+	false,
+	true,
+	false,
+	false,
+	true,
+	false,
+	false,
+	true,
+}
+
+var rulePaths = [...]string{
+	"",
+	"plus-expr/1",
+	"",
+	"minus-expr/1",
+	"",
+	"term/0/1",
+	"term/1/1",
+	"term/0",
+	"term/1",
+	"",
+	"factor/0",
+	"factor/1",
+	"",
+	"sign/0",
+	"",
+	"digit/1/0",
+	"digit/1",
+	"",
+	"quoted/0",
+	"quoted/2",
+	"",
+}
+
+// expr <- plus-expr / minus-expr / term
+func (p *parser) rule0(start int) (bool, int, *paza.Node) {
+	if ok, l, node := p.call(2, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(4, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(9, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	return false, 0, nil
+}
+
+// __parser__1 <- '+'
+func (p *parser) rule1(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != '+' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// plus-expr <- expr __parser__1 term
+func (p *parser) rule2(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(0, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(1, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(9, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__2 <- '-'
+func (p *parser) rule3(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != '-' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// minus-expr <- expr __parser__2 term
+func (p *parser) rule4(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(0, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(3, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(9, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__3 <- '*'
+func (p *parser) rule5(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != '*' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__4 <- '/'
+func (p *parser) rule6(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != '/' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__5 <- term __parser__3 factor
+func (p *parser) rule7(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(9, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(5, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(12, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__6 <- term __parser__4 factor
+func (p *parser) rule8(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(9, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(6, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(12, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// term <- __parser__5 / __parser__6 / factor
+func (p *parser) rule9(start int) (bool, int, *paza.Node) {
+	if ok, l, node := p.call(7, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(8, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(12, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	return false, 0, nil
+}
+
+// __parser__7 <- sign digit
+func (p *parser) rule10(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(14, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(17, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__8 <- sign quoted
+func (p *parser) rule11(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(14, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(20, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// factor <- __parser__7 / __parser__8
+func (p *parser) rule12(start int) (bool, int, *paza.Node) {
+	if ok, l, node := p.call(10, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(11, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	return false, 0, nil
+}
+
+// __parser__9 <- '-'
+func (p *parser) rule13(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != '-' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// sign <- __parser__9?
+func (p *parser) rule14(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	for {
+		ok, l, node := p.call(13, index)
+		if !ok {
+			break
+		}
+		index += l
+		subs = append(subs, node)
+		if len(subs) >= 1 {
+			break
+		}
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__10 <- `[0-9]`
+func (p *parser) rule15(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	if loc := regex15.FindIndex(p.text[start:]); loc != nil {
+		return true, loc[1], &paza.Node{Start: start, Len: loc[1]}
+	}
+	return false, 0, nil
+}
+
+var regex15 = regexp.MustCompile("\\A(?:[0-9])")
+
+// __parser__11 <- __parser__10+
+func (p *parser) rule16(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	for {
+		ok, l, node := p.call(15, index)
+		if !ok {
+			break
+		}
+		index += l
+		subs = append(subs, node)
+	}
+	if len(subs) < 1 {
+		return false, 0, nil
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// digit <- sign __parser__11
+func (p *parser) rule17(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(14, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(16, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__12 <- '('
+func (p *parser) rule18(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != '(' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__13 <- ')'
+func (p *parser) rule19(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != ')' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// quoted <- __parser__12 expr __parser__13
+func (p *parser) rule20(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	if ok, l, node := p.call(18, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(0, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(19, index); !ok {
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
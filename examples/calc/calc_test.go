@@ -0,0 +1,115 @@
+package calc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/reusee/paza"
+)
+
+func TestGenerated(t *testing.T) {
+	text, err := ioutil.ReadFile("calc.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := paza.ParseGrammar(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := paza.GenerateGo(buf, g, "calc"); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile("calc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), src) {
+		t.Fatal("calc.go is out of date, run go generate")
+	}
+}
+
+func TestCall(t *testing.T) {
+	text, err := ioutil.ReadFile("calc.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := paza.NewSet()
+	if err := set.LoadGrammar(text); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		"",
+		"1",
+		"1+2",
+		"1-2*3",
+		"(1)/1*(3-2)",
+		"(1)/1**(3-2)",
+		"*(1)/1**(3-2)",
+		"((12+3)*(4/(5-6)))-7",
+		"1+(2",
+	} {
+		for _, name := range []string{"expr", "term", "factor", "quoted"} {
+			ok, l, node := set.Call(name, paza.NewInput([]byte(text)), 0)
+			genOk, genL, genNode := Call(name, []byte(text), 0)
+			if ok != genOk || l != genL {
+				t.Fatalf("%s %q: %v %d, generated %v %d", name, text, ok, l, genOk, genL)
+			}
+			if ok && !node.Equal(genNode) {
+				t.Fatalf("%s %q: tree not match", name, text)
+			}
+		}
+	}
+}
+
+func TestCallRandom(t *testing.T) {
+	text, err := ioutil.ReadFile("calc.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := paza.NewSet()
+	if err := set.LoadGrammar(text); err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(42))
+	chars := []byte("0123456789+-*/()")
+	for i := 0; i < 20000; i++ {
+		text := make([]byte, r.Intn(12))
+		for j := range text {
+			text[j] = chars[r.Intn(len(chars))]
+		}
+		for _, name := range []string{"expr", "term", "factor"} {
+			ok, l, node := set.Call(name, paza.NewInput(text), 0)
+			genOk, genL, genNode := Call(name, text, 0)
+			if ok != genOk || l != genL {
+				t.Fatalf("%s %q: %v %d, generated %v %d", name, text, ok, l, genOk, genL)
+			}
+			if ok && !node.Equal(genNode) {
+				t.Fatalf("%s %q: tree not match", name, text)
+			}
+		}
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	text, err := ioutil.ReadFile("calc.peg")
+	if err != nil {
+		b.Fatal(err)
+	}
+	set := paza.NewSet()
+	if err := set.LoadGrammar(text); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Call("expr", paza.NewInput([]byte("((12+3)*(4/(5-6)))-7")), 0)
+	}
+}
+
+func BenchmarkGenerated(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Call("expr", []byte("((12+3)*(4/(5-6)))-7"), 0)
+	}
+}
@@ -0,0 +1,4 @@
+// Package calc is a parser generated from calc.peg by paza gen.
+package calc
+
+//go:generate go run ../../cmd/paza gen -pkg calc -o calc.go calc.peg
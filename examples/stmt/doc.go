@@ -0,0 +1,4 @@
+// Package stmt is a parser generated from stmt.peg by paza gen, with cuts.
+package stmt
+
+//go:generate go run ../../cmd/paza gen -pkg stmt -o stmt.go stmt.peg
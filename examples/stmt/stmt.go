@@ -0,0 +1,494 @@
+// Code generated by paza gen. DO NOT EDIT.
+
+package stmt
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/reusee/paza"
+)
+
+// Call matches the named rule at start, like paza.Set.Call.
+func Call(name string, text []byte, start int) (bool, int, *paza.Node) {
+	p := &parser{
+		text: text,
+	}
+	switch name {
+	case "file":
+		return p.call(0, start)
+	case "stmt":
+		return p.call(13, start)
+	case "ident":
+		return p.call(15, start)
+	}
+	panic("parser not found: " + name)
+}
+
+type stackEntry struct {
+	rule   int
+	start  int
+	ok     bool
+	length int
+	node   *paza.Node
+	used   bool
+}
+
+type parser struct {
+	text  []byte
+	stack []stackEntry
+	cut   bool
+	// a failure after a cut fails all later calls
+	aborted bool
+}
+
+func (p *parser) call(rule int, start int) (bool, int, *paza.Node) {
+	if p.aborted {
+		return false, 0, nil
+	}
+	// search stack
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		mem := p.stack[i]
+		if mem.rule == rule && mem.start == start {
+			p.stack[i].used = true
+			return mem.ok, mem.length, named(mem.node, rule)
+		}
+	}
+	index := len(p.stack)
+	p.stack = append(p.stack, stackEntry{
+		rule:  rule,
+		start: start,
+	})
+	// pop the entry, a later call must not see a result of an unfinished growth
+	defer func() {
+		p.stack = p.stack[:index]
+	}()
+	// find the right bound
+	lastOk := false
+	lastLen := 0
+	var lastNode *paza.Node
+	for {
+		ok, l, node := p.dispatch(rule, start)
+		p.stack = p.stack[:index+1]
+		if !ok {
+			return false, 0, nil
+		}
+		if !p.stack[index].used {
+			return ok, l, named(node, rule)
+		}
+		if l < lastLen {
+			return lastOk, lastLen, named(lastNode, rule)
+		} else if l == lastLen {
+			return ok, l, named(node, rule)
+		}
+		lastOk = ok
+		lastLen = l
+		lastNode = node
+		p.stack[index].ok = ok
+		p.stack[index].length = l
+		p.stack[index].node = node
+	}
+}
+
+func named(node *paza.Node, rule int) *paza.Node {
+	if node != nil {
+		node.Name = ruleNames[rule]
+		node.Named = namedRules[rule]
+		node.Path = rulePaths[rule]
+	}
+	return node
+}
+
+func (p *parser) dispatch(rule int, start int) (bool, int, *paza.Node) {
+	switch rule {
+	case 0:
+		return p.rule0(start)
+	case 1:
+		return p.rule1(start)
+	case 2:
+		return p.rule2(start)
+	case 3:
+		return p.rule3(start)
+	case 4:
+		return p.rule4(start)
+	case 5:
+		return p.rule5(start)
+	case 6:
+		return p.rule6(start)
+	case 7:
+		return p.rule7(start)
+	case 8:
+		return p.rule8(start)
+	case 9:
+		return p.rule9(start)
+	case 10:
+		return p.rule10(start)
+	case 11:
+		return p.rule11(start)
+	case 12:
+		return p.rule12(start)
+	case 13:
+		return p.rule13(start)
+	case 14:
+		return p.rule14(start)
+	case 15:
+		return p.rule15(start)
+	}
+	panic("bad rule")
+}
+
+var ruleNames = [...]string{
+	"file",
+	"literal",
+	"cut",
+	"rune",
+	"rune",
+	"literal",
+	"cut",
+	"rune",
+	"rune",
+	"rune",
+	"concat",
+	"concat",
+	"concat",
+	"stmt",
+	"regex",
+	"ident",
+}
+
+var namedRules = [...]bool{
+	true,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	false,
+	true,
+	false,
+	true,
+}
+
+var rulePaths = [...]string{
+	"",
+	"stmt/0/0",
+	"stmt/0/1",
+	"stmt/0/2",
+	"stmt/0/4",
+	"stmt/1/0",
+	"stmt/1/1",
+	"stmt/1/2",
+	"stmt/1/4",
+	"stmt/2/1",
+	"stmt/0",
+	"stmt/1",
+	"stmt/2",
+	"",
+	"ident/0",
+	"",
+}
+
+// file <- stmt*
+func (p *parser) rule0(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	for {
+		ok, l, node := p.call(13, index)
+		if !ok {
+			break
+		}
+		index += l
+		subs = append(subs, node)
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__1 <- "if"
+func (p *parser) rule1(start int) (bool, int, *paza.Node) {
+	if !bytes.HasPrefix(p.text[start:], []byte("if")) {
+		return false, 0, nil
+	}
+	return true, 2, &paza.Node{Start: start, Len: 2}
+}
+
+// __parser__2 <- ^
+func (p *parser) rule2(start int) (bool, int, *paza.Node) {
+	p.cut = true
+	return true, 0, nil
+}
+
+// __parser__3 <- ' '
+func (p *parser) rule3(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != ' ' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__4 <- ';'
+func (p *parser) rule4(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != ';' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__5 <- "while"
+func (p *parser) rule5(start int) (bool, int, *paza.Node) {
+	if !bytes.HasPrefix(p.text[start:], []byte("while")) {
+		return false, 0, nil
+	}
+	return true, 5, &paza.Node{Start: start, Len: 5}
+}
+
+// __parser__6 <- ^
+func (p *parser) rule6(start int) (bool, int, *paza.Node) {
+	p.cut = true
+	return true, 0, nil
+}
+
+// __parser__7 <- ' '
+func (p *parser) rule7(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != ' ' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__8 <- ';'
+func (p *parser) rule8(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != ';' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__9 <- ';'
+func (p *parser) rule9(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	r, l := utf8.DecodeRune(p.text[start:])
+	if r != ';' || l == 1 && r == utf8.RuneError {
+		return false, 0, nil
+	}
+	return true, l, &paza.Node{Start: start, Len: l}
+}
+
+// __parser__10 <- __parser__1 __parser__2 __parser__3 ident __parser__4
+func (p *parser) rule10(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	outerCut := p.cut
+	p.cut = false
+	if ok, l, node := p.call(1, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(2, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(3, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(15, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(4, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	p.cut = outerCut
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__11 <- __parser__5 __parser__6 __parser__7 ident __parser__8
+func (p *parser) rule11(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	outerCut := p.cut
+	p.cut = false
+	if ok, l, node := p.call(5, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(6, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(7, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(15, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(8, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	p.cut = outerCut
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// __parser__12 <- ident __parser__9
+func (p *parser) rule12(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	outerCut := p.cut
+	p.cut = false
+	if ok, l, node := p.call(15, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	if ok, l, node := p.call(9, index); !ok {
+		if p.cut {
+			p.aborted = true
+		}
+		p.cut = outerCut
+		return false, 0, nil
+	} else {
+		index += l
+		subs = append(subs, node)
+	}
+	p.cut = outerCut
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
+
+// stmt <- __parser__10 / __parser__11 / __parser__12
+func (p *parser) rule13(start int) (bool, int, *paza.Node) {
+	if ok, l, node := p.call(10, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(11, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	if ok, l, node := p.call(12, start); ok {
+		return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}
+	}
+	return false, 0, nil
+}
+
+// __parser__13 <- `[a-z]`
+func (p *parser) rule14(start int) (bool, int, *paza.Node) {
+	if start >= len(p.text) {
+		return false, 0, nil
+	}
+	if loc := regex14.FindIndex(p.text[start:]); loc != nil {
+		return true, loc[1], &paza.Node{Start: start, Len: loc[1]}
+	}
+	return false, 0, nil
+}
+
+var regex14 = regexp.MustCompile("\\A(?:[a-z])")
+
+// ident <- __parser__13+
+func (p *parser) rule15(start int) (bool, int, *paza.Node) {
+	index := start
+	var subs []*paza.Node
+	for {
+		ok, l, node := p.call(14, index)
+		if !ok {
+			break
+		}
+		index += l
+		subs = append(subs, node)
+	}
+	if len(subs) < 1 {
+		return false, 0, nil
+	}
+	return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}
+}
@@ -0,0 +1,62 @@
+package stmt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/reusee/paza"
+)
+
+func TestGenerated(t *testing.T) {
+	text, err := ioutil.ReadFile("stmt.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := paza.ParseGrammar(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := paza.GenerateGo(buf, g, "stmt"); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile("stmt.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), src) {
+		t.Fatal("stmt.go is out of date, run go generate")
+	}
+}
+
+func TestCall(t *testing.T) {
+	text, err := ioutil.ReadFile("stmt.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := paza.NewSet()
+	if err := set.LoadGrammar(text); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{
+		"",
+		"if foo;",
+		"foo;if;",
+		"foo;if 1;",
+		"while;",
+		"ifx;",
+		"if a;while b;c;",
+	} {
+		for _, name := range []string{"file", "stmt"} {
+			ok, l, node := set.Call(name, paza.NewInput([]byte(text)), 0)
+			genOk, genL, genNode := Call(name, []byte(text), 0)
+			if ok != genOk || l != genL {
+				t.Fatalf("%s %q: %v %d, generated %v %d", name, text, ok, l, genOk, genL)
+			}
+			if ok && !node.Equal(genNode) {
+				t.Fatalf("%s %q: tree not match", name, text)
+			}
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package paza
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type genRule struct {
+	name      string
+	anonymous bool
+	// node path of anonymous rules
+	path string
+	// operands of combinators are *RefExpr
+	expr Expr
+}
+
+type generator struct {
+	rules  []*genRule
+	ids    map[string]int
+	serial int
+	bytes  bool
+	regexp bool
+	utf8   bool
+	cut    bool
+}
+
+// GenerateGo writes a Go source file implementing the grammar without closures or map lookups.
+// Parse trees match those of a new Set loaded with AddGrammar(g), including node names of anonymous parsers.
+// g may come from ParseGrammar or Set.Grammar.
+// Generated parsers fail the match on invalid UTF-8, like InvalidUTF8Fail.
+func GenerateGo(w io.Writer, g *Grammar, pkg string) error {
+	gen := &generator{
+		ids: make(map[string]int),
+	}
+	for _, rule := range g.Rules {
+		if _, ok := gen.ids[rule.Name]; ok {
+			return fmt.Errorf("duplicated rule: %s", rule.Name)
+		}
+		gen.ids[rule.Name] = -1
+	}
+	for _, rule := range g.Rules {
+		expr, err := gen.lower(rule.Expr, rule.Name)
+		if err != nil {
+			return err
+		}
+		gen.add(rule.Name, expr, "")
+	}
+	for _, rule := range gen.rules {
+		for _, name := range Refs(rule.expr) {
+			if _, ok := gen.ids[name]; !ok {
+				return fmt.Errorf("undefined rule: %s", name)
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	gen.file(buf, pkg)
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// add adds a rule, path is empty for named rules
+func (g *generator) add(name string, expr Expr, path string) {
+	g.ids[name] = len(g.rules)
+	g.rules = append(g.rules, &genRule{
+		name:      name,
+		anonymous: path != "",
+		path:      path,
+		expr:      expr,
+	})
+}
+
+// lower mirrors the order Set.compile and getNames register anonymous parsers,
+// path is the node path of e
+func (g *generator) lower(e Expr, path string) (Expr, error) {
+	switch e := e.(type) {
+	case *RefExpr:
+		return &ChoiceExpr{Exprs: []Expr{e}}, nil
+	case *ConcatExpr:
+		exprs, err := g.operands(e.Exprs, path)
+		return &ConcatExpr{Exprs: exprs}, err
+	case *ChoiceExpr:
+		exprs, err := g.operands(e.Exprs, path)
+		return &ChoiceExpr{Exprs: exprs}, err
+	case *RepeatExpr:
+		exprs, err := g.operands([]Expr{e.Expr}, path)
+		if err != nil {
+			return nil, err
+		}
+		return &RepeatExpr{Min: e.Min, Max: e.Max, Expr: exprs[0]}, nil
+	case *PredicateExpr:
+		exprs, err := g.operands([]Expr{e.Expr}, path)
+		if err != nil {
+			return nil, err
+		}
+		return &PredicateExpr{Not: e.Not, Expr: exprs[0]}, nil
+	case *RegexExpr:
+		g.regexp = true
+	case *RuneExpr:
+		g.utf8 = true
+	case *AnyRuneExpr:
+		g.utf8 = true
+	case *LiteralExpr:
+		g.bytes = true
+	case *ByteInExpr:
+		g.bytes = true
+	case *ByteRangeExpr:
+	case *CutExpr:
+		g.cut = true
+	default:
+		return nil, fmt.Errorf("unknown expression type: %T", e)
+	}
+	return e, nil
+}
+
+func (g *generator) operands(exprs []Expr, path string) ([]Expr, error) {
+	ret := make([]Expr, len(exprs))
+	for i, e := range exprs {
+		if _, ok := e.(*RefExpr); ok {
+			ret[i] = e
+			continue
+		}
+		lowered, err := g.lower(e, path+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = lowered
+	}
+	// anonymous parsers are named after all operands are built
+	for i, e := range exprs {
+		if _, ok := e.(*RefExpr); ok {
+			continue
+		}
+		g.serial++
+		name := "__parser__" + strconv.Itoa(g.serial)
+		g.add(name, ret[i], path+"/"+strconv.Itoa(i))
+		ret[i] = &RefExpr{Name: name}
+	}
+	return ret, nil
+}
+
+func (g *generator) id(e Expr) int {
+	return g.ids[e.(*RefExpr).Name]
+}
+
+func (g *generator) file(w io.Writer, pkg string) {
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+	p("// Code generated by paza gen. DO NOT EDIT.")
+	p("")
+	p("package %s", pkg)
+	p("")
+	p("import (")
+	if g.bytes {
+		p(`"bytes"`)
+	}
+	if g.regexp {
+		p(`"regexp"`)
+	}
+	if g.utf8 {
+		p(`"unicode/utf8"`)
+	}
+	p("")
+	p(`"github.com/reusee/paza"`)
+	p(")")
+	p("")
+	p(`// Call matches the named rule at start, like paza.Set.Call.
+func Call(name string, text []byte, start int) (bool, int, *paza.Node) {
+	p := &parser{
+		text: text,
+	}
+	switch name {`)
+	for id, rule := range g.rules {
+		if rule.anonymous {
+			continue
+		}
+		p("case %q:", rule.name)
+		p("return p.call(%d, start)", id)
+	}
+	p(`}
+	panic("parser not found: " + name)
+}
+
+type stackEntry struct {
+	rule   int
+	start  int
+	ok     bool
+	length int
+	node   *paza.Node
+	used   bool
+}
+
+type parser struct {
+	text  []byte
+	stack []stackEntry`)
+	if g.cut {
+		p("cut bool")
+		p("// a failure after a cut fails all later calls")
+		p("aborted bool")
+	}
+	p(`}
+
+func (p *parser) call(rule int, start int) (bool, int, *paza.Node) {`)
+	if g.cut {
+		p(`if p.aborted {
+		return false, 0, nil
+	}`)
+	}
+	p(`// search stack
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		mem := p.stack[i]
+		if mem.rule == rule && mem.start == start {
+			p.stack[i].used = true
+			return mem.ok, mem.length, named(mem.node, rule)
+		}
+	}
+	index := len(p.stack)
+	p.stack = append(p.stack, stackEntry{
+		rule:  rule,
+		start: start,
+	})
+	// pop the entry, a later call must not see a result of an unfinished growth
+	defer func() {
+		p.stack = p.stack[:index]
+	}()
+	// find the right bound
+	lastOk := false
+	lastLen := 0
+	var lastNode *paza.Node
+	for {
+		ok, l, node := p.dispatch(rule, start)
+		p.stack = p.stack[:index+1]
+		if !ok {
+			return false, 0, nil
+		}
+		if !p.stack[index].used {
+			return ok, l, named(node, rule)
+		}
+		if l < lastLen {
+			return lastOk, lastLen, named(lastNode, rule)
+		} else if l == lastLen {
+			return ok, l, named(node, rule)
+		}
+		lastOk = ok
+		lastLen = l
+		lastNode = node
+		p.stack[index].ok = ok
+		p.stack[index].length = l
+		p.stack[index].node = node
+	}
+}
+
+func named(node *paza.Node, rule int) *paza.Node {
+	if node != nil {
+		node.Name = ruleNames[rule]
+		node.Named = namedRules[rule]
+		node.Path = rulePaths[rule]
+	}
+	return node
+}
+
+func (p *parser) dispatch(rule int, start int) (bool, int, *paza.Node) {
+	switch rule {`)
+	for id := range g.rules {
+		p("case %d:", id)
+		p("return p.rule%d(start)", id)
+	}
+	p(`}
+	panic("bad rule")
+}
+`)
+	p("var ruleNames = [...]string{")
+	for _, rule := range g.rules {
+		if rule.anonymous {
+			p("%q,", NodeName(rule.expr))
+		} else {
+			p("%q,", rule.name)
+		}
+	}
+	p("}")
+	p("")
+	p("var namedRules = [...]bool{")
+	for _, rule := range g.rules {
+		p("%v,", !rule.anonymous)
+	}
+	p("}")
+	p("")
+	p("var rulePaths = [...]string{")
+	for _, rule := range g.rules {
+		p("%q,", rule.path)
+	}
+	p("}")
+	p("")
+	for id, rule := range g.rules {
+		g.rule(p, id, rule)
+	}
+}
+
+func (g *generator) rule(p func(string, ...interface{}), id int, rule *genRule) {
+	p("// %s <- %s", rule.name, strings.Replace(rule.expr.String(), "\n", `\n`, -1))
+	p("func (p *parser) rule%d(start int) (bool, int, *paza.Node) {", id)
+	switch e := rule.expr.(type) {
+	case *ConcatExpr:
+		p("index := start")
+		p("var subs []*paza.Node")
+		if g.cut {
+			p("outerCut := p.cut")
+			p("p.cut = false")
+		}
+		for _, sub := range e.Exprs {
+			p("if ok, l, node := p.call(%d, index); !ok {", g.id(sub))
+			if g.cut {
+				p("if p.cut {")
+				p("p.aborted = true")
+				p("}")
+				p("p.cut = outerCut")
+			}
+			p("return false, 0, nil")
+			p("} else {")
+			p("index += l")
+			p("subs = append(subs, node)")
+			p("}")
+		}
+		if g.cut {
+			p("p.cut = outerCut")
+		}
+		p("return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}")
+	case *ChoiceExpr:
+		for _, sub := range e.Exprs {
+			p("if ok, l, node := p.call(%d, start); ok {", g.id(sub))
+			p("return ok, l, &paza.Node{Start: start, Len: l, Subs: []*paza.Node{node}}")
+			p("}")
+		}
+		p("return false, 0, nil")
+	case *RepeatExpr:
+		p("index := start")
+		p("var subs []*paza.Node")
+		p("for {")
+		p("ok, l, node := p.call(%d, index)", g.id(e.Expr))
+		p("if !ok {")
+		p("break")
+		p("}")
+		p("index += l")
+		p("subs = append(subs, node)")
+		if e.Max > 0 {
+			p("if len(subs) >= %d {", e.Max)
+			p("break")
+			p("}")
+		}
+		p("}")
+		if e.Min > 0 {
+			p("if len(subs) < %d {", e.Min)
+			p("return false, 0, nil")
+			p("}")
+		}
+		p("return true, index - start, &paza.Node{Start: start, Len: index - start, Subs: subs}")
+	case *PredicateExpr:
+		if e.Not {
+			p("if ok, _, _ := p.call(%d, start); !ok {", g.id(e.Expr))
+		} else {
+			p("if ok, _, _ := p.call(%d, start); ok {", g.id(e.Expr))
+		}
+		p("return true, 0, nil")
+		p("}")
+		p("return false, 0, nil")
+	case *RegexExpr:
+		p("if start >= len(p.text) {")
+		p("return false, 0, nil")
+		p("}")
+		p("if loc := regex%d.FindIndex(p.text[start:]); loc != nil {", id)
+		p("return true, loc[1], &paza.Node{Start: start, Len: loc[1]}")
+		p("}")
+		p("return false, 0, nil")
+	case *RuneExpr:
+		p("if start >= len(p.text) {")
+		p("return false, 0, nil")
+		p("}")
+		p("r, l := utf8.DecodeRune(p.text[start:])")
+		p("if r != %s || l == 1 && r == utf8.RuneError {", strconv.QuoteRune(e.Rune))
+		p("return false, 0, nil")
+		p("}")
+		p("return true, l, &paza.Node{Start: start, Len: l}")
+	case *AnyRuneExpr:
+		p("if start >= len(p.text) {")
+		p("return false, 0, nil")
+		p("}")
+		p("r, l := utf8.DecodeRune(p.text[start:])")
+		p("if l == 1 && r == utf8.RuneError {")
+		p("return false, 0, nil")
+		p("}")
+		p("return true, l, &paza.Node{Start: start, Len: l}")
+	case *LiteralExpr:
+		p("if !bytes.HasPrefix(p.text[start:], []byte(%q)) {", e.Text)
+		p("return false, 0, nil")
+		p("}")
+		p("return true, %d, &paza.Node{Start: start, Len: %d}", len(e.Text), len(e.Text))
+	case *ByteInExpr:
+		p("if start >= len(p.text) || bytes.IndexByte([]byte(%q), p.text[start]) < 0 {", e.Bytes)
+		p("return false, 0, nil")
+		p("}")
+		p("return true, 1, &paza.Node{Start: start, Len: 1}")
+	case *ByteRangeExpr:
+		p("if start >= len(p.text) || p.text[start] < %d || p.text[start] > %d {", e.Left, e.Right)
+		p("return false, 0, nil")
+		p("}")
+		p("return true, 1, &paza.Node{Start: start, Len: 1}")
+	case *CutExpr:
+		p("p.cut = true")
+		p("return true, 0, nil")
+	}
+	p("}")
+	p("")
+	if e, ok := rule.expr.(*RegexExpr); ok {
+		p("var regex%d = regexp.MustCompile(%q)", id, `\A(?:`+e.Pattern+`)`)
+		p("")
+	}
+}
@@ -0,0 +1,42 @@
+package paza
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := GenerateGo(buf, calcSet().Grammar(), "calc"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "// mul-expr <- term mul-op factor\n") {
+		t.Fatal("bad output")
+	}
+
+	g, err := ParseGrammar([]byte("a <- 'x' ^ 'y' / 'z'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := GenerateGo(buf, g, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "p.aborted = true") {
+		t.Fatal("cut not generated")
+	}
+
+	g, _ = ParseGrammar([]byte("a <- b"))
+	if err := GenerateGo(buf, g, "foo"); err == nil || err.Error() != "undefined rule: b" {
+		t.Fatal(err)
+	}
+
+	set := NewSet()
+	set.Add("a", func(input *Input, start int) (bool, int, *Node) {
+		return false, 0, nil
+	})
+	if err := GenerateGo(buf, set.Grammar(), "foo"); err == nil || err.Error() != "unknown expression type: *paza.CustomExpr" {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,301 @@
+package paza
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Generator produces random sentences of a set grammar.
+type Generator struct {
+	// rule nesting depth after which the shortest derivations are chosen
+	MaxDepth int
+	// maximum repetitions added to the minimum of a Repeat or regex repetition
+	MaxRepeat int
+	// attempts before giving up when generated text is rejected by the parser, e.g. because of predicates
+	MaxTries int
+	set      *Set
+	rand     *rand.Rand
+	costs    map[string]int
+}
+
+// an unbounded cost
+const infiniteCost = 1 << 30
+
+func NewGenerator(set *Set, seed int64) *Generator {
+	g := &Generator{
+		MaxDepth:  8,
+		MaxRepeat: 3,
+		MaxTries:  100,
+		set:       set,
+		rand:      rand.New(rand.NewSource(seed)),
+		costs:     make(map[string]int),
+	}
+	// minimum rule nesting needed to derive a sentence from each rule
+	for name := range set.rules {
+		g.costs[name] = infiniteCost
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, e := range set.rules {
+			if c := g.cost(e); c < g.costs[name] {
+				g.costs[name] = c
+				changed = true
+			}
+		}
+	}
+	return g
+}
+
+func (g *Generator) cost(e Expr) int {
+	switch e := e.(type) {
+	case *RefExpr:
+		c, ok := g.costs[e.Name]
+		if !ok || c == infiniteCost {
+			return infiniteCost
+		}
+		return c + 1
+	case *ConcatExpr:
+		max := 0
+		for _, sub := range e.Exprs {
+			if c := g.cost(sub); c > max {
+				max = c
+			}
+		}
+		return max
+	case *ChoiceExpr:
+		min := infiniteCost
+		for _, sub := range e.Exprs {
+			if c := g.cost(sub); c < min {
+				min = c
+			}
+		}
+		return min
+	case *RepeatExpr:
+		if e.Min == 0 {
+			return 0
+		}
+		return g.cost(e.Expr)
+	case *ActionExpr:
+		return g.cost(e.Expr)
+	case *RecoverExpr:
+		return g.cost(e.Expr)
+	case *OperatorsExpr:
+		return g.cost(e.Operand)
+	}
+	return 0
+}
+
+// Generate returns a random text matched by the start rule as a whole.
+func (g *Generator) Generate(start string) ([]byte, error) {
+	if _, ok := g.set.rules[start]; !ok {
+		return nil, fmt.Errorf("rule not defined: %s", start)
+	}
+	if g.costs[start] == infiniteCost {
+		return nil, fmt.Errorf("rule %s derives no finite sentence", start)
+	}
+	for try := 0; try < g.MaxTries; try++ {
+		buf := new(bytes.Buffer)
+		if err := g.generate(buf, &RefExpr{Name: start}, 0); err != nil {
+			return nil, err
+		}
+		if _, err := g.set.Parse(start, NewInput(buf.Bytes())); err == nil {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("no sentence accepted by %s in %d tries", start, g.MaxTries)
+}
+
+func (g *Generator) repeat(min, max int, depth int) int {
+	n := min
+	if depth < g.MaxDepth {
+		n += g.rand.Intn(g.MaxRepeat + 1)
+	}
+	if max > 0 && n > max {
+		n = max
+	}
+	return n
+}
+
+// choose returns a random expression, or a cheapest one beyond the depth limit
+func (g *Generator) choose(exprs []Expr, depth int) Expr {
+	if depth < g.MaxDepth {
+		return exprs[g.rand.Intn(len(exprs))]
+	}
+	var best Expr
+	min := infiniteCost + 1
+	for _, e := range exprs {
+		if c := g.cost(e); c < min {
+			best = e
+			min = c
+		}
+	}
+	return best
+}
+
+func (g *Generator) generate(buf *bytes.Buffer, e Expr, depth int) error {
+	switch e := e.(type) {
+	case *RefExpr:
+		sub, ok := g.set.rules[e.Name]
+		if !ok {
+			return fmt.Errorf("rule not defined: %s", e.Name)
+		}
+		return g.generate(buf, sub, depth+1)
+	case *ConcatExpr:
+		for _, sub := range e.Exprs {
+			if err := g.generate(buf, sub, depth); err != nil {
+				return err
+			}
+		}
+	case *ChoiceExpr:
+		return g.generate(buf, g.choose(e.Exprs, depth), depth)
+	case *RepeatExpr:
+		for n := g.repeat(e.Min, e.Max, depth); n > 0; n-- {
+			if err := g.generate(buf, e.Expr, depth); err != nil {
+				return err
+			}
+		}
+	case *PredicateExpr, *CutExpr:
+		// checked by parsing the result
+	case *RegexExpr:
+		re, err := syntax.Parse(e.Pattern, syntax.Perl)
+		if err != nil {
+			return err
+		}
+		g.regex(buf, re.Simplify(), depth)
+	case *RuneExpr:
+		buf.WriteRune(e.Rune)
+	case *AnyRuneExpr:
+		buf.WriteRune(g.printable())
+	case *LiteralExpr:
+		buf.WriteString(e.Text)
+	case *ByteInExpr:
+		buf.WriteByte(e.Bytes[g.rand.Intn(len(e.Bytes))])
+	case *ByteRangeExpr:
+		buf.WriteByte(e.Left + byte(g.rand.Intn(int(e.Right-e.Left)+1)))
+	case *ActionExpr:
+		return g.generate(buf, e.Expr, depth)
+	case *RecoverExpr:
+		return g.generate(buf, e.Expr, depth)
+	case *OperatorsExpr:
+		return g.operators(buf, e, len(e.Levels)-1, depth)
+	default:
+		return fmt.Errorf("cannot generate %s", e)
+	}
+	return nil
+}
+
+func (g *Generator) operators(buf *bytes.Buffer, e *OperatorsExpr, k int, depth int) error {
+	if k < 0 || depth >= g.MaxDepth {
+		return g.generate(buf, e.Operand, depth)
+	}
+	level := e.Levels[k]
+	var prefix, infix, postfix []Expr
+	for _, op := range level.Ops {
+		switch op.Fixity {
+		case Prefix:
+			prefix = append(prefix, op.Expr)
+		case Infix:
+			infix = append(infix, op.Expr)
+		case Postfix:
+			postfix = append(postfix, op.Expr)
+		}
+	}
+	// operators are applied at random, one more depth each
+	if len(prefix) > 0 && g.rand.Intn(3) == 0 {
+		if err := g.generate(buf, g.choose(prefix, depth), depth); err != nil {
+			return err
+		}
+	}
+	if err := g.operators(buf, e, k-1, depth+1); err != nil {
+		return err
+	}
+	if len(postfix) > 0 && g.rand.Intn(3) == 0 {
+		if err := g.generate(buf, g.choose(postfix, depth), depth); err != nil {
+			return err
+		}
+	}
+	if len(infix) > 0 && g.rand.Intn(2) == 0 {
+		if err := g.generate(buf, g.choose(infix, depth), depth); err != nil {
+			return err
+		}
+		return g.operators(buf, e, k-1, depth+1)
+	}
+	return nil
+}
+
+// printable returns a random printable ASCII rune
+func (g *Generator) printable() rune {
+	return rune(' ' + g.rand.Intn('~'-' '+1))
+}
+
+func (g *Generator) regex(buf *bytes.Buffer, re *syntax.Regexp, depth int) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.rand.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			buf.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		buf.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		buf.WriteRune(g.printable())
+	case syntax.OpCapture:
+		g.regex(buf, re.Sub[0], depth)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.regex(buf, sub, depth)
+		}
+	case syntax.OpAlternate:
+		g.regex(buf, re.Sub[g.rand.Intn(len(re.Sub))], depth)
+	case syntax.OpStar:
+		for n := g.repeat(0, -1, depth); n > 0; n-- {
+			g.regex(buf, re.Sub[0], depth)
+		}
+	case syntax.OpPlus:
+		for n := g.repeat(1, -1, depth); n > 0; n-- {
+			g.regex(buf, re.Sub[0], depth)
+		}
+	case syntax.OpQuest:
+		for n := g.repeat(0, 1, depth); n > 0; n-- {
+			g.regex(buf, re.Sub[0], depth)
+		}
+	case syntax.OpRepeat:
+		for n := g.repeat(re.Min, re.Max, depth); n > 0; n-- {
+			g.regex(buf, re.Sub[0], depth)
+		}
+	}
+	// empty width assertions are checked by parsing the result
+}
+
+// classRune picks a rune from class ranges, printable ASCII preferred
+func (g *Generator) classRune(ranges []rune) rune {
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) > 0 {
+		ranges = ascii
+	}
+	for {
+		i := g.rand.Intn(len(ranges)/2) * 2
+		r := ranges[i] + rune(g.rand.Int63n(int64(ranges[i+1]-ranges[i])+1))
+		if utf8.ValidRune(r) {
+			return r
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package paza
+
+import "testing"
+
+func TestGenerate(t *testing.T) {
+	operators := NewSet()
+	operators.Add("expr", operators.Operators(
+		operators.OrdChoice(
+			operators.Regex(`[0-9]+`),
+			operators.Concat(operators.Rune('('), "expr", operators.Rune(')')),
+		),
+		Level{RightAssoc, []Op{{"neg", Prefix, operators.Rune('-')}}},
+		Level{LeftAssoc, []Op{{"fact", Postfix, operators.Rune('!')}}},
+		Level{LeftAssoc, []Op{{"mul", Infix, operators.Rune('*')}}},
+		Level{LeftAssoc, []Op{{"add", Infix, operators.Rune('+')}}},
+	))
+	predicates := NewSet()
+	if err := predicates.LoadGrammar([]byte(`
+stmts <- stmt+ !.
+stmt <- !kw ident ';' / kw ';'
+kw <- 'if' / 'else'
+ident <- ` + "`[a-z]{1,3}|(?i:x)`" + `
+`)); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		set   *Set
+		start string
+		tries int
+	}{
+		{calcSet(), "expr", 1}, // no predicates, every generated text is accepted
+		{operators, "expr", 100},
+		{predicates, "stmts", 100},
+	}
+	for _, c := range cases {
+		g := NewGenerator(c.set, 1)
+		g.MaxTries = c.tries
+		texts := make(map[string]bool)
+		for i := 0; i < 200; i++ {
+			text, err := g.Generate(c.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.set.Parse(c.start, NewInput(text)); err != nil {
+				t.Fatalf("%q: %v", text, err)
+			}
+			texts[string(text)] = true
+		}
+		if len(texts) < 50 {
+			t.Fatalf("%s: only %d different texts", c.start, len(texts))
+		}
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(`
+a <- 'x' a
+b <- c
+c <- 'x' &'y'
+`)); err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(set, 1)
+	if _, err := g.Generate("a"); err == nil || err.Error() != "rule a derives no finite sentence" {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := g.Generate("b"); err == nil || err.Error() != "no sentence accepted by b in 100 tries" {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := g.Generate("d"); err == nil || err.Error() != "rule not defined: d" {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,465 @@
+package paza
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Grammar struct {
+	Rules []*Rule
+}
+
+type Rule struct {
+	Name string
+	Expr Expr
+}
+
+// Expr is a node of a structural grammar description.
+// String returns the expression in PEG syntax.
+type Expr interface {
+	String() string
+}
+
+type RefExpr struct {
+	Name string
+}
+
+type ConcatExpr struct {
+	Exprs []Expr
+}
+
+type ChoiceExpr struct {
+	Exprs []Expr
+}
+
+type RepeatExpr struct {
+	Min  int
+	Max  int
+	Expr Expr
+}
+
+type PredicateExpr struct {
+	Not  bool
+	Expr Expr
+}
+
+type RegexExpr struct {
+	Pattern string
+}
+
+type RuneExpr struct {
+	Rune rune
+}
+
+// AnyRuneExpr matches any character.
+// Like the other field-less expressions, it is not zero-size so distinct ones have distinct pointers.
+type AnyRuneExpr struct {
+	_ byte
+}
+
+type LiteralExpr struct {
+	Text string
+}
+
+type ByteInExpr struct {
+	Bytes []byte
+}
+
+type ByteRangeExpr struct {
+	Left  byte
+	Right byte
+}
+
+// CutExpr commits the enclosing sequence.
+type CutExpr struct {
+	_ byte
+}
+
+// RecoverExpr matches Expr, or skips input through Sync.
+type RecoverExpr struct {
+	Expr Expr
+	Sync Expr
+}
+
+// ActionExpr is an expression with an Action attached.
+type ActionExpr struct {
+	Expr Expr
+}
+
+// CustomExpr describes a Parser not built by the combinators.
+type CustomExpr struct {
+	_ byte
+}
+
+// precedence levels for printing
+const (
+	precChoice = iota
+	precConcat
+	precPrefix
+	precSuffix
+)
+
+func precedence(e Expr) int {
+	switch e := e.(type) {
+	case *ActionExpr:
+		return precedence(e.Expr)
+	case *ChoiceExpr:
+		return precChoice
+	case *ConcatExpr:
+		return precConcat
+	case *PredicateExpr:
+		return precPrefix
+	}
+	return precSuffix
+}
+
+func group(e Expr, prec int) string {
+	if precedence(e) < prec {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+func (e *RefExpr) String() string {
+	return e.Name
+}
+
+func (e *ConcatExpr) String() string {
+	parts := make([]string, 0, len(e.Exprs))
+	for _, sub := range e.Exprs {
+		parts = append(parts, group(sub, precPrefix))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e *ChoiceExpr) String() string {
+	parts := make([]string, 0, len(e.Exprs))
+	for _, sub := range e.Exprs {
+		parts = append(parts, group(sub, precConcat))
+	}
+	return strings.Join(parts, " / ")
+}
+
+func (e *RepeatExpr) String() string {
+	sub := group(e.Expr, precSuffix)
+	switch {
+	case e.Min == 0 && e.Max == 1:
+		return sub + "?"
+	case e.Min == 0 && e.Max <= 0:
+		return sub + "*"
+	case e.Min == 1 && e.Max <= 0:
+		return sub + "+"
+	case e.Max <= 0:
+		return sub + "{" + strconv.Itoa(e.Min) + ",}"
+	case e.Min == e.Max:
+		return sub + "{" + strconv.Itoa(e.Min) + "}"
+	}
+	return sub + "{" + strconv.Itoa(e.Min) + "," + strconv.Itoa(e.Max) + "}"
+}
+
+func (e *PredicateExpr) String() string {
+	if e.Not {
+		return "!" + group(e.Expr, precSuffix)
+	}
+	return "&" + group(e.Expr, precSuffix)
+}
+
+func (e *RegexExpr) String() string {
+	return "`" + e.Pattern + "`"
+}
+
+func (e *RuneExpr) String() string {
+	return strconv.QuoteRune(e.Rune)
+}
+
+func (e *AnyRuneExpr) String() string {
+	return "."
+}
+
+func (e *CutExpr) String() string {
+	return "^"
+}
+
+func (e *LiteralExpr) String() string {
+	return strconv.Quote(e.Text)
+}
+
+func (e *ByteInExpr) String() string {
+	return byteClass(e.Bytes)
+}
+
+func (e *ByteRangeExpr) String() string {
+	return byteClass([]byte{e.Left, '-', e.Right})
+}
+
+// String returns a call like form, recovery has no PEG syntax.
+func (e *RecoverExpr) String() string {
+	return "recover(" + e.Expr.String() + ", " + e.Sync.String() + ")"
+}
+
+// String returns the wrapped expression, actions have no PEG syntax.
+func (e *ActionExpr) String() string {
+	return e.Expr.String()
+}
+
+func (e *CustomExpr) String() string {
+	return "<custom>"
+}
+
+func (g *Grammar) String() string {
+	var b strings.Builder
+	for _, rule := range g.Rules {
+		b.WriteString(rule.Name)
+		b.WriteString(" <- ")
+		b.WriteString(rule.Expr.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// record pairs a combinator parser with its expression
+func (s *Set) record(e Expr, parser Parser) *Pattern {
+	return &Pattern{
+		Expr:   e,
+		Parser: parser,
+	}
+}
+
+func (s *Set) exprOf(parser interface{}) Expr {
+	switch parser := parser.(type) {
+	case string:
+		return &RefExpr{Name: parser}
+	case *Pattern:
+		return parser.Expr
+	}
+	return &CustomExpr{}
+}
+
+// nameExpr returns the expression of an operand registered by getNames
+func (s *Set) nameExpr(name string) Expr {
+	if isAnonymous(name) {
+		return s.rules[name]
+	}
+	return &RefExpr{Name: name}
+}
+
+func (s *Set) namesExprs(names []string) []Expr {
+	ret := make([]Expr, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, s.nameExpr(name))
+	}
+	return ret
+}
+
+// NodeName returns the name of nodes of anonymous parsers with the expression.
+func NodeName(e Expr) string {
+	switch e := e.(type) {
+	case *RefExpr:
+		return "ref"
+	case *ConcatExpr:
+		return "concat"
+	case *ChoiceExpr:
+		return "choice"
+	case *RepeatExpr:
+		return "repeat"
+	case *PredicateExpr:
+		if e.Not {
+			return "not"
+		}
+		return "and"
+	case *RegexExpr:
+		return "regex"
+	case *RuneExpr:
+		return "rune"
+	case *AnyRuneExpr:
+		return "any"
+	case *LiteralExpr:
+		return "literal"
+	case *ByteInExpr:
+		return "byte-in"
+	case *ByteRangeExpr:
+		return "byte-range"
+	case *CutExpr:
+		return "cut"
+	case *RecoverExpr:
+		return "recover"
+	case *ActionExpr:
+		return "action"
+	case *OperatorsExpr:
+		return "operators"
+	}
+	return "custom"
+}
+
+// setPaths sets paths of anonymous parsers of sub expressions of e,
+// an anonymous parser used by several rules keeps the first path
+func (s *Set) setPaths(path string, e Expr) {
+	for i, sub := range subExprs(e) {
+		subPath := path + "/" + strconv.Itoa(i)
+		for _, name := range s.anonymous[sub] {
+			if _, ok := s.paths[name]; !ok {
+				s.paths[name] = subPath
+			}
+		}
+		s.setPaths(subPath, sub)
+	}
+}
+
+func isAnonymous(name string) bool {
+	return strings.HasPrefix(name, "__parser__")
+}
+
+// Rules returns names of added rules in order, anonymous parsers excluded.
+func (s *Set) Rules() []string {
+	var names []string
+	for _, name := range s.names {
+		if !isAnonymous(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Expr returns the expression of the named rule, or nil if not found.
+func (s *Set) Expr(name string) Expr {
+	return s.rules[name]
+}
+
+// Grammar returns the rules of the set as a grammar.
+func (s *Set) Grammar() *Grammar {
+	g := new(Grammar)
+	for _, name := range s.Rules() {
+		g.Rules = append(g.Rules, &Rule{
+			Name: name,
+			Expr: s.rules[name],
+		})
+	}
+	return g
+}
+
+// Referrers returns names of rules referencing the named rule.
+func (s *Set) Referrers(name string) []string {
+	var names []string
+	for _, rule := range s.Rules() {
+		for _, ref := range Refs(s.rules[rule]) {
+			if ref == name {
+				names = append(names, rule)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Refs returns names of rules referenced by the expression, in order of appearance.
+func Refs(e Expr) (names []string) {
+	WalkExpr(e, func(e Expr) {
+		if ref, ok := e.(*RefExpr); ok {
+			names = append(names, ref.Name)
+		}
+	})
+	return
+}
+
+// WalkExpr calls fn for e and all its sub expressions in depth-first order.
+func WalkExpr(e Expr, fn func(Expr)) {
+	fn(e)
+	for _, sub := range subExprs(e) {
+		WalkExpr(sub, fn)
+	}
+}
+
+func subExprs(e Expr) []Expr {
+	switch e := e.(type) {
+	case *ConcatExpr:
+		return e.Exprs
+	case *ChoiceExpr:
+		return e.Exprs
+	case *RepeatExpr:
+		return []Expr{e.Expr}
+	case *PredicateExpr:
+		return []Expr{e.Expr}
+	case *ActionExpr:
+		return []Expr{e.Expr}
+	case *RecoverExpr:
+		return []Expr{e.Expr, e.Sync}
+	case *OperatorsExpr:
+		ret := []Expr{e.Operand}
+		for _, level := range e.Levels {
+			for _, op := range level.Ops {
+				ret = append(ret, op.Expr)
+			}
+		}
+		return ret
+	}
+	return nil
+}
+
+// AddGrammar adds parsers for all rules of the grammar.
+func (s *Set) AddGrammar(g *Grammar) {
+	for _, rule := range g.Rules {
+		s.Add(rule.Name, s.compile(rule.Expr))
+	}
+}
+
+func (s *Set) compile(e Expr) *Pattern {
+	switch e := e.(type) {
+	case *RefExpr:
+		return s.OrdChoice(e.Name)
+	case *ConcatExpr:
+		return s.Concat(s.operands(e.Exprs)...)
+	case *ChoiceExpr:
+		return s.OrdChoice(s.operands(e.Exprs)...)
+	case *RepeatExpr:
+		return s.Repeat(e.Min, e.Max, s.operand(e.Expr))
+	case *PredicateExpr:
+		if e.Not {
+			return s.NotPredicate(s.operand(e.Expr))
+		}
+		return s.Predicate(s.operand(e.Expr))
+	case *RegexExpr:
+		return s.Regex(e.Pattern)
+	case *RuneExpr:
+		return s.Rune(e.Rune)
+	case *AnyRuneExpr:
+		return s.AnyRune()
+	case *CutExpr:
+		return s.Cut()
+	case *RecoverExpr:
+		return s.Recover(s.operand(e.Expr), s.operand(e.Sync))
+	case *OperatorsExpr:
+		operand := s.operand(e.Operand)
+		levels := make([]Level, 0, len(e.Levels))
+		for _, level := range e.Levels {
+			l := Level{Assoc: level.Assoc}
+			for _, op := range level.Ops {
+				l.Ops = append(l.Ops, Op{Name: op.Name, Fixity: op.Fixity, Parser: s.operand(op.Expr)})
+			}
+			levels = append(levels, l)
+		}
+		return s.Operators(operand, levels...)
+	case *LiteralExpr:
+		return s.Literal(e.Text)
+	case *ByteInExpr:
+		return s.ByteIn(e.Bytes)
+	case *ByteRangeExpr:
+		return s.ByteRange(e.Left, e.Right)
+	}
+	panic(fmt.Sprintf("unknown expression type: %T", e))
+}
+
+func (s *Set) operand(e Expr) interface{} {
+	if ref, ok := e.(*RefExpr); ok {
+		return ref.Name
+	}
+	return s.compile(e)
+}
+
+func (s *Set) operands(exprs []Expr) []interface{} {
+	ret := make([]interface{}, 0, len(exprs))
+	for _, e := range exprs {
+		ret = append(ret, s.operand(e))
+	}
+	return ret
+}
@@ -0,0 +1,202 @@
+package paza
+
+import "testing"
+
+func TestLoadGrammar(t *testing.T) {
+	set := NewSet()
+	err := set.LoadGrammar([]byte(`
+# calc
+expr   <- expr '+' term
+        / expr '-' term
+        / term
+term   <- term "*" factor / term '/' factor / factor
+factor <- ` + "`[0-9]+`" + ` / '(' expr ')'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, set, []testCase{
+		{[]byte("1"), "expr", true, 1},
+		{[]byte("1+1"), "expr", true, 3},
+		{[]byte("(1)/1*(3-2)"), "expr", true, 11},
+		{[]byte("(1)/1**(3-2)"), "expr", true, 5},
+		{[]byte("*(1)/1**(3-2)"), "expr", false, 0},
+		{[]byte(""), "expr", false, 0},
+	})
+
+	set = NewSet()
+	err = set.LoadGrammar([]byte(`
+A <- B 'a' / 'd'
+B <- C 'b' / 'e'
+C <- A 'c' / 'f'
+kw <- 'if' ![a-z_] / 'else' &' ' . / "fo\"o\t" / '白'
+opt <- 'a'? [b-c]+ 'd'* 'e'{2,3}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, set, []testCase{
+		{[]byte("dcba"), "A", true, 4},
+		{[]byte("fbac"), "C", true, 4},
+		{[]byte("if"), "kw", true, 2},
+		{[]byte("if("), "kw", true, 2},
+		{[]byte("iff"), "kw", false, 0},
+		{[]byte("else "), "kw", true, 5},
+		{[]byte("elsex"), "kw", false, 0},
+		{[]byte("fo\"o\t"), "kw", true, 5},
+		{[]byte("白"), "kw", true, 3},
+		{[]byte("abee"), "opt", true, 4},
+		{[]byte("cbddeeee"), "opt", true, 7},
+		{[]byte("aee"), "opt", false, 0},
+		{[]byte("be"), "opt", false, 0},
+	})
+}
+
+func TestGrammarString(t *testing.T) {
+	text := "a <- b c / !d ^ (e / f)* / &(g h)+ .\n" +
+		"b <- 'x' \"yz\" `[0-9]+` `[a-z]` x{2} x{1,} x{2,3} x?\n"
+	g, err := ParseGrammar([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "a <- b c / !d ^ (e / f)* / &(g h)+ .\n" +
+		"b <- 'x' \"yz\" `[0-9]+` `[a-z]` x{2} x+ x{2,3} x?\n"
+	if g.String() != expected {
+		t.Fatalf("got %s", g.String())
+	}
+	g2, err := ParseGrammar([]byte(g.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2.String() != expected {
+		t.Fatalf("got %s", g2.String())
+	}
+}
+
+func TestGrammarError(t *testing.T) {
+	cases := []struct {
+		text string
+		err  string
+	}{
+		{"", "grammar 1:1: no rules"},
+		{"# only comment\n", "grammar 2:1: no rules"},
+		{"a b", "grammar 1:3: expected '<-'"},
+		{"<- b", "grammar 1:1: expected rule name"},
+		{"a <- ", "grammar 1:6: expected expression"},
+		{"a <- b /", "grammar 1:9: expected expression"},
+		{"a <- (b", "grammar 1:8: expected ')'"},
+		{"a <- b\n  ) c", "grammar 2:3: expected rule name"},
+		{"a <- 'b", "grammar 1:6: unterminated literal"},
+		{"a <- ''", "grammar 1:6: empty literal"},
+		{"a <- [b", "grammar 1:6: unterminated character class"},
+		{"a <- `b", "grammar 1:6: unterminated regular expression"},
+		{"a <- `(`", "grammar 1:6: error parsing regexp: missing closing ): `(`"},
+		{"a <- b{3,2}", "grammar 1:7: bad repetition bounds"},
+		{"a <- b{x}", "grammar 1:8: expected number"},
+		{"a <- b\na <- c", "grammar 2:1: duplicated rule: a"},
+		{"a <- b @", "grammar 1:8: unexpected '@'"},
+	}
+	for _, c := range cases {
+		_, err := ParseGrammar([]byte(c.text))
+		if err == nil || err.Error() != c.err {
+			t.Fatalf("%q: got %v", c.text, err)
+		}
+	}
+}
+
+func TestSetGrammar(t *testing.T) {
+	set := calcSet()
+	set.Add("custom", func(input *Input, start int) (bool, int, *Node) {
+		return false, 0, nil
+	})
+	set.Add("misc", set.Concat(
+		set.Predicate(set.ByteIn([]byte("ab"))),
+		set.NotPredicate(set.ByteRange('0', '9')),
+		set.OneOrMore(set.Literal("if")),
+		set.Repeat(2, 3, set.Rune('x')),
+		set.ZeroOrMore("custom"),
+	))
+	expected := "plus-op <- '+'\n" +
+		"plus-expr <- expr plus-op term\n" +
+		"minus-op <- '-'\n" +
+		"minus-expr <- expr minus-op term\n" +
+		"expr <- plus-expr / minus-expr / term\n" +
+		"mul-op <- '*'\n" +
+		"mul-expr <- term mul-op factor\n" +
+		"div-op <- '/'\n" +
+		"div-expr <- term div-op factor\n" +
+		"term <- mul-expr / div-expr / factor\n" +
+		"digit <- `[0-9]+`\n" +
+		"left-quote <- '('\n" +
+		"right-quote <- ')'\n" +
+		"quoted <- left-quote expr right-quote\n" +
+		"factor <- digit / quoted\n" +
+		"custom <- <custom>\n" +
+		"misc <- &[ab] ![0-9] \"if\"+ 'x'{2,3} custom*\n"
+	if s := set.Grammar().String(); s != expected {
+		t.Fatalf("got\n%s", s)
+	}
+
+	choice, ok := set.Expr("term").(*ChoiceExpr)
+	if !ok || len(choice.Exprs) != 3 {
+		t.Fatal("term")
+	}
+	if set.Expr("foo") != nil {
+		t.Fatal("foo")
+	}
+
+	referrers := set.Referrers("expr")
+	if len(referrers) != 3 || referrers[0] != "plus-expr" || referrers[1] != "minus-expr" || referrers[2] != "quoted" {
+		t.Fatalf("%v", referrers)
+	}
+	if refs := Refs(set.Expr("misc")); len(refs) != 1 || refs[0] != "custom" {
+		t.Fatalf("%v", refs)
+	}
+}
+
+func TestSetGrammarRoundTrip(t *testing.T) {
+	text := "expr <- expr '+' term / term\n" +
+		"term <- `[0-9]+` / '(' expr ')' / &'x' \"xy\"? !. `[a-z]`{2,}\n" +
+		"alias <- term\n"
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(text)); err != nil {
+		t.Fatal(err)
+	}
+	if s := set.Grammar().String(); s != text {
+		t.Fatalf("got\n%s", s)
+	}
+}
+
+func TestExprOf(t *testing.T) {
+	set := NewSet()
+	set.Add("a", set.Concat(set.AnyRune(), set.AnyRune(), set.Cut(), set.Cut()))
+	exprs := set.Expr("a").(*ConcatExpr).Exprs
+	if exprs[0] == exprs[1] || exprs[2] == exprs[3] {
+		t.Fatal("expressions not distinct")
+	}
+	if _, ok := exprs[0].(*AnyRuneExpr); !ok {
+		t.Fatalf("got %T", exprs[0])
+	}
+	set.Add("b", func(input *Input, start int) (bool, int, *Node) {
+		return input.Text[start] == 'b', 1, nil
+	})
+	if _, ok := set.Expr("b").(*CustomExpr); !ok {
+		t.Fatalf("got %T", set.Expr("b"))
+	}
+	// custom parsers are not called when added, and not described by the combinators they call
+	n := 0
+	digit := set.Regex(`[0-9]`)
+	set.Add("nondigit", Parser(func(input *Input, start int) (bool, int, *Node) {
+		n++
+		if ok, _, _ := digit.Parser(input, start); ok || input.atEnd(start) {
+			return false, 0, nil
+		}
+		return true, 1, &Node{Start: start, Len: 1}
+	}))
+	if n != 0 {
+		t.Fatal("custom parser called")
+	}
+	if _, ok := set.Expr("nondigit").(*CustomExpr); !ok {
+		t.Fatalf("got %T", set.Expr("nondigit"))
+	}
+}
@@ -0,0 +1,81 @@
+package paza
+
+// Edit returns a new input with deleted bytes at offset replaced by inserted.
+// Memoized results of i that did not examine the changed text are reused by the new input,
+// shifted if they are after the change, so reparsing only redoes the affected calls.
+// Memoization must be enabled on i, Edit panics on reader inputs.
+func (i *Input) Edit(offset, deleted int, inserted []byte) *Input {
+	if i.reader != nil {
+		panic("edit on reader input")
+	}
+	text := make([]byte, 0, len(i.Text)-deleted+len(inserted))
+	text = append(text, i.Text[:offset]...)
+	text = append(text, inserted...)
+	text = append(text, i.Text[offset+deleted:]...)
+	input := NewInput(text)
+	input.Limits = i.Limits
+	input.InvalidUTF8 = i.InvalidUTF8
+	input.EnableMemo(i.memoLimit)
+	delta := len(inserted) - deleted
+	kept := make(map[*Node]*Node)
+	shifted := make(map[*Node]*Node)
+	for key, entry := range i.memo {
+		if entry.reach <= offset { // before the change
+			i.carry(input, entry.node, 0, kept)
+		} else if key.start >= offset+deleted { // after the change
+			key.start += delta
+			entry.reach += delta
+			entry.fail.farthest += delta
+			entry.node = i.carry(input, entry.node, delta, shifted)
+		} else {
+			continue
+		}
+		input.memo[key] = entry
+	}
+	return input
+}
+
+// carry returns node shifted by delta for input, with its values and errors
+func (i *Input) carry(input *Input, node *Node, delta int, carried map[*Node]*Node) *Node {
+	if node == nil {
+		return nil
+	}
+	if n, ok := carried[node]; ok {
+		return n
+	}
+	n := node
+	if delta != 0 {
+		n = &Node{
+			Name:  node.Name,
+			Named: node.Named,
+			Path:  node.Path,
+			Start: node.Start + delta,
+			Len:   node.Len,
+		}
+		if node.Subs != nil {
+			n.Subs = make([]*Node, 0, len(node.Subs))
+		}
+	}
+	carried[node] = n
+	for _, sub := range node.Subs {
+		sub = i.carry(input, sub, delta, carried)
+		if delta != 0 {
+			n.Subs = append(n.Subs, sub)
+		}
+	}
+	if value, ok := i.values[node]; ok {
+		if input.values == nil {
+			input.values = make(map[*Node]interface{})
+		}
+		input.values[n] = value
+	}
+	if err, ok := i.recovered[node]; ok {
+		if input.recovered == nil {
+			input.recovered = make(map[*Node]*ParseError)
+		}
+		e := *err
+		e.Offset += delta
+		input.recovered[n] = &e
+	}
+	return n
+}
@@ -0,0 +1,133 @@
+package paza
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	set := calcSet()
+	n := 0
+	digit := set.Regex(`[0-9]+`)
+	set.Add("digit", Parser(func(input *Input, start int) (bool, int, *Node) {
+		n++
+		return digit.Parser(input, start)
+	}))
+	r := rand.New(rand.NewSource(42))
+	chars := []byte("0123456789+-*/()")
+	randText := func(l int) []byte {
+		text := make([]byte, l)
+		for i := range text {
+			text[i] = chars[r.Intn(len(chars))]
+		}
+		return text
+	}
+	editCalls := 0
+	scratchCalls := 0
+	for i := 0; i < 200; i++ {
+		text := []byte("(1+2)*3-(45/6+7)*(8-9)+10*(11+12)-13/14")
+		input := NewInput(text)
+		input.EnableMemo(-1)
+		set.Call("expr", input, 0)
+		for j := 0; j < 10; j++ {
+			offset := r.Intn(len(input.Text) + 1)
+			deleted := r.Intn(len(input.Text)-offset+1) % 4
+			inserted := randText(r.Intn(4))
+			input = input.Edit(offset, deleted, inserted)
+			n = 0
+			ok, l, node := set.Call("expr", input, 0)
+			editCalls += n
+			n = 0
+			scratch := NewInput(input.Text)
+			scratch.EnableMemo(-1)
+			scratchOk, scratchL, scratchNode := set.Call("expr", scratch, 0)
+			scratchCalls += n
+			if ok != scratchOk || l != scratchL {
+				t.Fatalf("%q: got %v %d, expected %v %d", input.Text, ok, l, scratchOk, scratchL)
+			}
+			if ok && !node.Equal(scratchNode) {
+				t.Fatalf("%q: tree not match", input.Text)
+			}
+			_, err := set.Parse("expr", input)
+			_, expectedErr := set.Parse("expr", NewInput(input.Text))
+			if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+				t.Fatalf("%q: got %v, expected %v", input.Text, err, expectedErr)
+			}
+		}
+	}
+	if editCalls*2 > scratchCalls {
+		t.Fatalf("results not reused: %d %d", editCalls, scratchCalls)
+	}
+}
+
+func TestEditShift(t *testing.T) {
+	set := calcSet()
+	input := NewInput([]byte("1+(2*3)"))
+	input.EnableMemo(-1)
+	set.Call("expr", input, 0)
+	input = input.Edit(0, 1, []byte("45"))
+	if string(input.Text) != "45+(2*3)" {
+		t.Fatalf("got %q", input.Text)
+	}
+	entry, ok := input.memo[memoKey{"quoted", 3}]
+	if !ok {
+		t.Fatal("should be shifted")
+	}
+	if entry.node.Start != 3 || entry.node.Len != 5 || entry.node.Subs[1].Start != 4 {
+		t.Fatal("bad shifted node")
+	}
+	node, err := set.Parse("expr", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, expected := set.Call("expr", NewInput(input.Text), 0)
+	if !node.Equal(expected) {
+		t.Fatal("tree not match")
+	}
+}
+
+func TestEditError(t *testing.T) {
+	set := calcSet()
+	input := NewInput([]byte("1*(2+3"))
+	input.EnableMemo(-1)
+	set.Parse("expr", input)
+	input = input.Edit(0, 0, []byte("4+"))
+	_, err := set.Parse("expr", input)
+	if err == nil || err.Error() != "parse error at offset 8: expected '*', '/', '+', '-' or ')' (in expr > plus-expr > term > mul-expr > factor > quoted > expr > plus-expr > term > mul-expr > mul-op)" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestEditRegex(t *testing.T) {
+	set := NewSet()
+	set.Add("s", set.Concat(set.Regex("a(bcdef)?"), set.Regex(".*")))
+	r := rand.New(rand.NewSource(42))
+	chars := []byte("abcdefXZ")
+	for i := 0; i < 500; i++ {
+		input := NewInput([]byte("abcdeXZ"))
+		input.EnableMemo(-1)
+		set.Call("s", input, 0)
+		for j := 0; j < 10; j++ {
+			offset := r.Intn(len(input.Text) + 1)
+			deleted := r.Intn(len(input.Text)-offset+1) % 3
+			inserted := []byte{chars[r.Intn(len(chars))]}
+			input = input.Edit(offset, deleted, inserted)
+			ok, l, node := set.Call("s", input, 0)
+			scratch := NewInput(input.Text)
+			scratch.EnableMemo(-1)
+			scratchOk, scratchL, scratchNode := set.Call("s", scratch, 0)
+			if ok != scratchOk || l != scratchL || ok && !node.Equal(scratchNode) {
+				t.Fatalf("%q: got %v %d, expected %v %d", input.Text, ok, l, scratchOk, scratchL)
+			}
+		}
+	}
+	input := NewInput([]byte("abcdeXZ"))
+	input.EnableMemo(-1)
+	set.Call("s", input, 0)
+	input = input.Edit(5, 1, []byte("f"))
+	_, _, node := set.Call("s", input, 0)
+	if node.Subs[0].Len != 6 {
+		t.Fatalf("got %d", node.Subs[0].Len)
+	}
+}
@@ -0,0 +1,74 @@
+package paza
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limits bounds resources used by a parse, zero means no limit.
+type Limits struct {
+	// nesting depth of rule calls
+	MaxDepth int
+	// number of rule calls
+	MaxCalls int
+	// left recursion growth iterations of a single call
+	MaxGrowth int
+}
+
+type LimitError struct {
+	Limit  string
+	Max    int
+	Rule   string
+	Offset int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit %d exceeded calling %s at offset %d", e.Limit, e.Max, e.Rule, e.Offset)
+}
+
+// how many calls between context checks
+const contextCheckInterval = 1024
+
+// Err returns the error aborting the parse, if any.
+// Once set, all rule calls on the input fail.
+func (i *Input) Err() error {
+	return i.err
+}
+
+func (i *Input) abort(err error) {
+	if i.err == nil {
+		i.err = err
+	}
+}
+
+// enter checks limits before calling a rule
+func (i *Input) enter(name string, start int) bool {
+	if i.err != nil {
+		return false
+	}
+	if i.ctx != nil && i.numCalls%contextCheckInterval == 0 {
+		if err := i.ctx.Err(); err != nil {
+			i.abort(err)
+			return false
+		}
+	}
+	i.numCalls++
+	if i.Limits.MaxCalls > 0 && i.numCalls > i.Limits.MaxCalls {
+		i.abort(&LimitError{"calls", i.Limits.MaxCalls, name, start})
+		return false
+	}
+	if i.Limits.MaxDepth > 0 && len(i.calls) >= i.Limits.MaxDepth {
+		i.abort(&LimitError{"depth", i.Limits.MaxDepth, name, start})
+		return false
+	}
+	return true
+}
+
+// ParseContext is like Parse but stops with the context error once ctx is done.
+func (s *Set) ParseContext(ctx context.Context, name string, input *Input) (*Node, error) {
+	input.ctx = ctx
+	defer func() {
+		input.ctx = nil
+	}()
+	return s.Parse(name, input)
+}
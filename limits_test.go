@@ -0,0 +1,99 @@
+package paza
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	set := calcSet()
+	cases := []struct {
+		text   string
+		limits Limits
+		err    string
+	}{
+		{"((((1))))", Limits{MaxDepth: 20}, ""},
+		{"((((1))))", Limits{MaxDepth: 10}, "depth limit 10 exceeded calling expr at offset 2"},
+		{"1+2", Limits{MaxCalls: 100}, ""},
+		{"1+2", Limits{MaxCalls: 10}, "calls limit 10 exceeded calling factor at offset 0"},
+		{"1+2+3", Limits{MaxGrowth: 3}, ""},
+		{"1+2+3", Limits{MaxGrowth: 2}, "growth limit 2 exceeded calling expr at offset 0"},
+	}
+	for _, c := range cases {
+		input := NewInput([]byte(c.text))
+		input.Limits = c.limits
+		node, err := set.Parse("expr", input)
+		if c.err == "" {
+			if err != nil || node == nil {
+				t.Fatalf("%q: %v", c.text, err)
+			}
+			continue
+		}
+		if _, ok := err.(*LimitError); !ok || err.Error() != c.err {
+			t.Fatalf("%q: %v", c.text, err)
+		}
+		if input.Err() != err {
+			t.Fatal("input error")
+		}
+		// aborted input fails all calls
+		if ok, _, _ := set.Call("factor", input, 0); ok {
+			t.Fatal("should fail")
+		}
+	}
+}
+
+func TestLimitsNotPredicate(t *testing.T) {
+	set := NewSet()
+	set.Add("a", set.Concat(set.NotPredicate(set.Rune('a')), set.Rune('b')))
+	input := NewInput([]byte("b"))
+	input.Limits.MaxCalls = 2
+	if _, err := set.Parse("a", input); err == nil {
+		t.Fatal("should fail")
+	}
+}
+
+type cancelTracer struct {
+	calls  int
+	after  int
+	cancel func()
+}
+
+func (t *cancelTracer) Enter(TraceEvent) {
+	t.calls++
+	if t.calls == t.after {
+		t.cancel()
+	}
+}
+
+func (t *cancelTracer) Exit(TraceEvent) {}
+
+func (t *cancelTracer) SeedGrow(TraceEvent) {}
+
+func TestParseContext(t *testing.T) {
+	set := backtrackSet()
+	text := bytes.Repeat([]byte("("), 30)
+	ctx, cancel := context.WithCancel(context.Background())
+	input := NewInput(text)
+	// cancel during the parse
+	tracer := &cancelTracer{after: 5000, cancel: cancel}
+	input.Tracer = tracer
+	_, err := set.ParseContext(ctx, "expr", input)
+	if err != context.Canceled {
+		t.Fatalf("got %v", err)
+	}
+	if tracer.calls > 5000+contextCheckInterval {
+		t.Fatalf("not stopped: %d", tracer.calls)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := set.ParseContext(ctx, "expr", NewInput([]byte("1"))); err != context.Canceled {
+		t.Fatalf("got %v", err)
+	}
+
+	node, err := set.ParseContext(context.Background(), "expr", NewInput([]byte("(1)")))
+	if err != nil || node.Len != 3 {
+		t.Fatalf("got %v", err)
+	}
+}
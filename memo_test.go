@@ -0,0 +1,88 @@
+package paza
+
+import "testing"
+
+func calcSet() *Set {
+	set := NewSet()
+	set.Add("expr", set.OrdChoice(
+		set.NamedConcat("plus-expr", "expr", set.NamedRune("plus-op", '+'), "term"),
+		set.NamedConcat("minus-expr", "expr", set.NamedRune("minus-op", '-'), "term"),
+		"term",
+	))
+	set.Add("term", set.OrdChoice(
+		set.NamedConcat("mul-expr", "term", set.NamedRune("mul-op", '*'), "factor"),
+		set.NamedConcat("div-expr", "term", set.NamedRune("div-op", '/'), "factor"),
+		"factor",
+	))
+	set.Add("factor", set.OrdChoice(
+		set.NamedRegex("digit", `[0-9]+`),
+		set.NamedConcat("quoted", set.NamedRune("left-quote", '('), "expr", set.NamedRune("right-quote", ')')),
+	))
+	return set
+}
+
+func TestMemo(t *testing.T) {
+	set := calcSet()
+	for _, text := range []string{
+		"1",
+		"1+2",
+		"1+2*3-4/5",
+		"(1+2)*(3-(4/5))",
+		"((1))+(2*(3))-4",
+		"1+",
+		"(1",
+		"",
+	} {
+		input := NewInput([]byte(text))
+		ok, l, node := set.Call("expr", input, 0)
+		memoInput := NewInput([]byte(text))
+		memoInput.EnableMemo(-1)
+		memoOk, memoL, memoNode := set.Call("expr", memoInput, 0)
+		if ok != memoOk || l != memoL {
+			t.Fatalf("%s: %v %d, memo %v %d", text, ok, l, memoOk, memoL)
+		}
+		if ok && !node.Equal(memoNode) {
+			t.Fatalf("%s: tree not match", text)
+		}
+	}
+}
+
+func TestMemoCalls(t *testing.T) {
+	set := backtrackSet()
+	n := 0
+	digit := set.Regex(`[0-9]+`)
+	set.Add("term", set.OrdChoice(
+		set.Concat(set.Rune('('), "expr", set.Rune(')')),
+		Parser(func(input *Input, start int) (bool, int, *Node) {
+			n++
+			return digit.Parser(input, start)
+		}),
+	))
+	text := []byte("((((1))))")
+
+	set.Call("expr", NewInput(text), 0)
+	plain := n
+	n = 0
+	input := NewInput(text)
+	input.EnableMemo(-1)
+	set.Call("expr", input, 0)
+	if n != 1 {
+		t.Fatalf("memo: %d calls", n)
+	}
+	if plain <= n {
+		t.Fatalf("plain: %d calls", plain)
+	}
+}
+
+func TestMemoLimit(t *testing.T) {
+	set := calcSet()
+	input := NewInput([]byte("(1+2)*(3-(4/5))"))
+	input.EnableMemo(4)
+	ok, l, _ := set.Call("expr", input, 0)
+	if !ok || l != 15 {
+		t.Fatalf("%v %d", ok, l)
+	}
+	if len(input.memo) > 4 {
+		t.Fatalf("memo size %d", len(input.memo))
+	}
+}
@@ -0,0 +1,227 @@
+package paza
+
+import "strings"
+
+type Assoc int
+
+const (
+	LeftAssoc Assoc = iota
+	RightAssoc
+	NonAssoc
+)
+
+type Fixity int
+
+const (
+	Infix Fixity = iota
+	Prefix
+	Postfix
+)
+
+// Op is an operator of an Operators level.
+// Name is used as the name of the nodes it builds.
+type Op struct {
+	Name   string
+	Fixity Fixity
+	Parser interface{}
+}
+
+type Level struct {
+	Assoc Assoc
+	Ops   []Op
+}
+
+// Operators parses expressions of operand and the operators in levels, tightest binding level first.
+// An infix operator node has the left operand, the operator and the right operand as Subs,
+// prefix and postfix nodes have the operator and the operand in input order.
+// Left associative operators nest to the left, right associative ones to the right,
+// and non associative ones apply at most once on a level.
+func (s *Set) Operators(operand interface{}, levels ...Level) *Pattern {
+	operandName := s.getNames(operand)[0]
+	expr := &OperatorsExpr{
+		Operand: s.nameExpr(operandName),
+	}
+	type op struct {
+		Op
+		name string
+		expr Expr
+	}
+	var ops [][]op
+	for _, level := range levels {
+		var levelOps []op
+		levelExpr := OperatorsLevel{Assoc: level.Assoc}
+		for _, o := range level.Ops {
+			name := s.getNames(o.Parser)[0]
+			e := s.nameExpr(name)
+			levelOps = append(levelOps, op{o, name, e})
+			levelExpr.Ops = append(levelExpr.Ops, OperatorExpr{
+				Name:   o.Name,
+				Fixity: o.Fixity,
+				Expr:   e,
+			})
+		}
+		ops = append(ops, levelOps)
+		expr.Levels = append(expr.Levels, levelExpr)
+	}
+
+	// match tries the operators of fixity on level k
+	match := func(input *Input, k int, fixity Fixity, pos int) (*op, int, *Node) {
+		for i := range ops[k] {
+			o := &ops[k][i]
+			if o.Fixity != fixity {
+				continue
+			}
+			ok, l, node := s.Call(o.name, input, pos)
+			input.cover(o.expr, ok)
+			if ok {
+				return o, l, node
+			}
+		}
+		return nil, 0, nil
+	}
+
+	var parse func(input *Input, k int, start int) (bool, int, *Node)
+	parse = func(input *Input, k int, start int) (bool, int, *Node) {
+		if k < 0 {
+			ok, l, node := s.Call(operandName, input, start)
+			input.cover(expr.Operand, ok)
+			return ok, l, node
+		}
+		assoc := levels[k].Assoc
+		// prefix operators
+		var left *Node
+		if o, l, opNode := match(input, k, Prefix, start); o != nil {
+			next := k
+			if assoc == NonAssoc {
+				next = k - 1
+			}
+			if ok, l2, node := parse(input, next, start+l); ok {
+				left = &Node{
+					Name:  o.Name,
+					Named: true,
+					Start: start,
+					Len:   l + l2,
+					Subs:  []*Node{opNode, node},
+				}
+			}
+		}
+		if left == nil {
+			ok, l, node := parse(input, k-1, start)
+			if !ok {
+				return false, 0, nil
+			}
+			left = node
+			if left == nil {
+				left = &Node{Start: start, Len: l}
+			}
+		}
+		// postfix operators
+		for {
+			o, l, opNode := match(input, k, Postfix, start+left.Len)
+			if o == nil {
+				break
+			}
+			left = &Node{
+				Name:  o.Name,
+				Named: true,
+				Start: start,
+				Len:   left.Len + l,
+				Subs:  []*Node{left, opNode},
+			}
+			if assoc == NonAssoc {
+				break
+			}
+		}
+		// infix operators
+		for {
+			o, l, opNode := match(input, k, Infix, start+left.Len)
+			if o == nil {
+				break
+			}
+			next := k - 1
+			if assoc == RightAssoc {
+				next = k
+			}
+			ok, l2, right := parse(input, next, start+left.Len+l)
+			if !ok {
+				break
+			}
+			if right == nil {
+				right = &Node{Start: start + left.Len + l, Len: l2}
+			}
+			left = &Node{
+				Name:  o.Name,
+				Named: true,
+				Start: start,
+				Len:   left.Len + l + l2,
+				Subs:  []*Node{left, opNode, right},
+			}
+			if assoc == NonAssoc {
+				// report a chained operator instead of leaving it unparsed silently
+				pos := start + left.Len
+				if o2, _, _ := match(input, k, Infix, pos); o2 != nil {
+					input.fail(pos, "end of non-associative "+o.Name)
+				}
+				break
+			}
+			if assoc == RightAssoc {
+				break
+			}
+		}
+		return true, left.Len, left
+	}
+
+	return s.record(expr, func(input *Input, start int) (bool, int, *Node) {
+		// operands and operators are retried from earlier positions
+		input.mark(start)
+		defer input.unmark()
+		ok, l, node := parse(input, len(levels)-1, start)
+		if !ok {
+			return false, 0, nil
+		}
+		return true, l, &Node{
+			Start: start,
+			Len:   l,
+			Subs:  []*Node{node},
+		}
+	})
+}
+
+func (s *Set) NamedOperators(name string, operand interface{}, levels ...Level) string {
+	s.Add(name, s.Operators(operand, levels...))
+	return name
+}
+
+// OperatorsExpr describes an Operators parser.
+type OperatorsExpr struct {
+	Operand Expr
+	Levels  []OperatorsLevel
+}
+
+type OperatorsLevel struct {
+	Assoc Assoc
+	Ops   []OperatorExpr
+}
+
+type OperatorExpr struct {
+	Name   string
+	Fixity Fixity
+	Expr   Expr
+}
+
+var assocNames = []string{"left", "right", "nonassoc"}
+
+var fixityNames = []string{"infix", "prefix", "postfix"}
+
+// String returns a call like form, operator tables have no PEG syntax.
+func (e *OperatorsExpr) String() string {
+	parts := []string{e.Operand.String()}
+	for _, level := range e.Levels {
+		var ops []string
+		for _, op := range level.Ops {
+			ops = append(ops, fixityNames[op.Fixity]+" "+op.Name+" "+group(op.Expr, precSuffix))
+		}
+		parts = append(parts, assocNames[level.Assoc]+"("+strings.Join(ops, ", ")+")")
+	}
+	return "operators(" + strings.Join(parts, ", ") + ")"
+}
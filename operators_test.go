@@ -0,0 +1,88 @@
+package paza
+
+import (
+	"strings"
+	"testing"
+)
+
+// sexpr renders operator trees, operands as their text
+func sexpr(node *Node, text []byte) string {
+	var subs []*Node
+	switch len(node.Subs) {
+	case 3:
+		subs = []*Node{node.Subs[0], node.Subs[2]}
+	case 2:
+		if node.Name == "neg" { // prefix
+			subs = node.Subs[1:]
+		} else {
+			subs = node.Subs[:1]
+		}
+	default:
+		return string(text[node.Start : node.Start+node.Len])
+	}
+	parts := []string{node.Name}
+	for _, sub := range subs {
+		parts = append(parts, sexpr(sub, text))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func TestOperators(t *testing.T) {
+	set := NewSet()
+	set.Add("expr", set.Operators(
+		set.OrdChoice(
+			set.NamedRegex("num", `[0-9]+`),
+			set.Concat(set.Rune('('), "expr", set.Rune(')')),
+		),
+		Level{RightAssoc, []Op{{"neg", Prefix, set.Rune('-')}}},
+		Level{LeftAssoc, []Op{{"fact", Postfix, set.Rune('!')}}},
+		Level{RightAssoc, []Op{{"pow", Infix, set.Rune('^')}}},
+		Level{LeftAssoc, []Op{{"mul", Infix, set.Rune('*')}, {"div", Infix, set.Rune('/')}}},
+		Level{LeftAssoc, []Op{{"add", Infix, set.Rune('+')}, {"sub", Infix, set.Rune('-')}}},
+		Level{NonAssoc, []Op{{"lt", Infix, set.Rune('<')}}},
+	))
+	cases := []struct {
+		text     string
+		expected string
+		len      int
+	}{
+		{"1", "1", 1},
+		{"1-2-3", "(sub (sub 1 2) 3)", 5},
+		{"2^3^4", "(pow 2 (pow 3 4))", 5},
+		{"1+2*3!", "(add 1 (mul 2 (fact 3)))", 6},
+		{"1*2+3/4", "(add (mul 1 2) (div 3 4))", 7},
+		{"--2^2", "(pow (neg (neg 2)) 2)", 5},
+		{"3!!", "(fact (fact 3))", 3},
+		{"1<2<3", "(lt 1 2)", 3},
+		{"(1+2)*3", "(mul (1+2) 3)", 7},
+		{"1+", "1", 1},
+	}
+	for _, c := range cases {
+		text := []byte(c.text)
+		ok, l, node := set.Call("expr", NewInput(text), 0)
+		if !ok || l != c.len {
+			t.Fatalf("%q: got %v %d", c.text, ok, l)
+		}
+		if node.Name != "expr" || len(node.Subs) != 1 {
+			t.Fatalf("%q: bad node", c.text)
+		}
+		if s := sexpr(node.Subs[0], text); s != c.expected {
+			t.Fatalf("%q: got %s", c.text, s)
+		}
+	}
+	if ok, _, _ := set.Call("expr", NewInput([]byte("+1")), 0); ok {
+		t.Fatal("should fail")
+	}
+	if _, err := set.Parse("expr", NewInput([]byte("1<2<3"))); err == nil ||
+		err.Error() != "parse error at offset 3: expected '!', '^', '*', '/', '+', '-', end of non-associative lt or end of input (in expr)" {
+		t.Fatalf("got %v", err)
+	}
+
+	if e := set.Expr("expr").String(); e != "operators(num / '(' expr ')', right(prefix neg '-'), left(postfix fact '!'), "+
+		"right(infix pow '^'), left(infix mul '*', infix div '/'), left(infix add '+', infix sub '-'), nonassoc(infix lt '<'))" {
+		t.Fatalf("got %s", e)
+	}
+	if errs := set.Check("expr"); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+}
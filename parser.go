@@ -1,24 +1,33 @@
 package paza
 
 import (
+	"bytes"
 	"regexp"
-	"unicode/utf8"
+	"strconv"
 )
 
-func (s *Set) Regex(re string) Parser {
-	regex := regexp.MustCompile(re)
-	return func(input *Input, start int) (bool, int, *Node) {
-		if start >= len(input.Text) {
+func (s *Set) Regex(re string) *Pattern {
+	anchored := regexp.MustCompile(`\A(?:` + re + `)`)
+	return s.record(&RegexExpr{Pattern: re}, func(input *Input, start int) (bool, int, *Node) {
+		if input.atEnd(start) {
+			input.fail(start, re)
 			return false, 0, nil
 		}
-		if loc := regex.FindIndex(input.Text[start:]); loc != nil && loc[0] == 0 {
+		var loc []int
+		if input.reader != nil || input.memo != nil { // read as needed, recording how far the match looked
+			loc = anchored.FindReaderIndex(&readerRunes{input, start})
+		} else {
+			loc = anchored.FindIndex(input.Text[start:])
+		}
+		if loc != nil {
 			return true, loc[1], &Node{
 				Start: start,
 				Len:   loc[1],
 			}
 		}
+		input.fail(start, re)
 		return false, 0, nil
-	}
+	})
 }
 
 func (s *Set) NamedRegex(name string, re string) string {
@@ -26,23 +35,23 @@ func (s *Set) NamedRegex(name string, re string) string {
 	return name
 }
 
-func (s *Set) Rune(r rune) Parser {
-	return func(input *Input, start int) (bool, int, *Node) {
-		if start >= len(input.Text) {
+func (s *Set) Rune(r rune) *Pattern {
+	expected := strconv.QuoteRune(r)
+	return s.record(&RuneExpr{Rune: r}, func(input *Input, start int) (bool, int, *Node) {
+		if input.atEnd(start) {
+			input.fail(start, expected)
 			return false, 0, nil
 		}
-		ru, l := utf8.DecodeRune(input.Text[start:])
-		if ru == utf8.RuneError {
-			panic("utf8 decode error")
-		}
-		if ru != r {
+		ru, l, ok := input.decodeRune(start)
+		if !ok || ru != r {
+			input.fail(start, expected)
 			return false, 0, nil
 		}
 		return true, l, &Node{
 			Start: start,
 			Len:   l,
 		}
-	}
+	})
 }
 
 func (s *Set) NamedRune(name string, r rune) string {
@@ -50,12 +59,57 @@ func (s *Set) NamedRune(name string, r rune) string {
 	return name
 }
 
-func (s *Set) ByteIn(bs []byte) Parser {
-	return func(input *Input, start int) (bool, int, *Node) {
-		if start >= len(input.Text) {
+func (s *Set) AnyRune() *Pattern {
+	return s.record(&AnyRuneExpr{}, func(input *Input, start int) (bool, int, *Node) {
+		if input.atEnd(start) {
+			input.fail(start, "any character")
+			return false, 0, nil
+		}
+		_, l, ok := input.decodeRune(start)
+		if !ok {
+			input.fail(start, "any character")
 			return false, 0, nil
 		}
-		b := input.Text[start]
+		return true, l, &Node{
+			Start: start,
+			Len:   l,
+		}
+	})
+}
+
+func (s *Set) NamedAnyRune(name string) string {
+	s.Add(name, s.AnyRune())
+	return name
+}
+
+func (s *Set) Literal(lit string) *Pattern {
+	expected := strconv.Quote(lit)
+	bs := []byte(lit)
+	return s.record(&LiteralExpr{Text: lit}, func(input *Input, start int) (bool, int, *Node) {
+		if !bytes.HasPrefix(input.rest(start, len(bs)), bs) {
+			input.fail(start, expected)
+			return false, 0, nil
+		}
+		return true, len(bs), &Node{
+			Start: start,
+			Len:   len(bs),
+		}
+	})
+}
+
+func (s *Set) NamedLiteral(name string, lit string) string {
+	s.Add(name, s.Literal(lit))
+	return name
+}
+
+func (s *Set) ByteIn(bs []byte) *Pattern {
+	expected := byteClass(bs)
+	return s.record(&ByteInExpr{Bytes: bs}, func(input *Input, start int) (bool, int, *Node) {
+		if input.atEnd(start) {
+			input.fail(start, expected)
+			return false, 0, nil
+		}
+		b := input.byteAt(start)
 		for _, bt := range bs {
 			if bt == b {
 				return true, 1, &Node{
@@ -64,8 +118,9 @@ func (s *Set) ByteIn(bs []byte) Parser {
 				}
 			}
 		}
+		input.fail(start, expected)
 		return false, 0, nil
-	}
+	})
 }
 
 func (s *Set) NamedByteIn(name string, bs []byte) string {
@@ -73,20 +128,23 @@ func (s *Set) NamedByteIn(name string, bs []byte) string {
 	return name
 }
 
-func (s *Set) ByteRange(left, right byte) Parser {
-	return func(input *Input, start int) (bool, int, *Node) {
-		if start >= len(input.Text) {
+func (s *Set) ByteRange(left, right byte) *Pattern {
+	expected := byteClass([]byte{left, '-', right})
+	return s.record(&ByteRangeExpr{Left: left, Right: right}, func(input *Input, start int) (bool, int, *Node) {
+		if input.atEnd(start) {
+			input.fail(start, expected)
 			return false, 0, nil
 		}
-		b := input.Text[start]
+		b := input.byteAt(start)
 		if b >= left && b <= right {
 			return true, 1, &Node{
 				Start: start,
 				Len:   1,
 			}
 		}
+		input.fail(start, expected)
 		return false, 0, nil
-	}
+	})
 }
 
 func (s *Set) NamedByteRange(name string, left, right byte) string {
@@ -94,17 +152,29 @@ func (s *Set) NamedByteRange(name string, left, right byte) string {
 	return name
 }
 
-func (s *Set) Concat(parsers ...interface{}) Parser {
+func (s *Set) Concat(parsers ...interface{}) *Pattern {
 	names := s.getNames(parsers...)
-	return func(input *Input, start int) (bool, int, *Node) {
+	exprs := s.namesExprs(names)
+	return s.record(&ConcatExpr{Exprs: exprs}, func(input *Input, start int) (bool, int, *Node) {
 		index := start
 		var subs []*Node
-		for _, name := range names {
-			if ok, l, node := s.Call(name, input, index); !ok {
+		outerCut := input.cut
+		input.cut = false
+		defer func() {
+			input.cut = outerCut
+		}()
+		for i, name := range names {
+			ok, l, node := s.Call(name, input, index)
+			input.cover(exprs[i], ok)
+			if !ok {
+				if input.cut { // committed
+					input.abort(input.parseError())
+				}
 				return false, 0, nil
 			} else {
 				index += l
 				subs = append(subs, node)
+				input.progress(index)
 			}
 		}
 		return true, index - start, &Node{
@@ -112,7 +182,7 @@ func (s *Set) Concat(parsers ...interface{}) Parser {
 			Len:   index - start,
 			Subs:  subs,
 		}
-	}
+	})
 }
 
 func (s *Set) NamedConcat(name string, parsers ...interface{}) string {
@@ -120,11 +190,16 @@ func (s *Set) NamedConcat(name string, parsers ...interface{}) string {
 	return name
 }
 
-func (s *Set) OrdChoice(parsers ...interface{}) Parser {
+func (s *Set) OrdChoice(parsers ...interface{}) *Pattern {
 	names := s.getNames(parsers...)
-	return func(input *Input, start int) (bool, int, *Node) {
-		for _, name := range names {
-			if ok, l, node := s.Call(name, input, start); ok {
+	exprs := s.namesExprs(names)
+	return s.record(&ChoiceExpr{Exprs: exprs}, func(input *Input, start int) (bool, int, *Node) {
+		input.mark(start)
+		defer input.unmark()
+		for i, name := range names {
+			ok, l, node := s.Call(name, input, start)
+			input.cover(exprs[i], ok)
+			if ok {
 				return ok, l, &Node{
 					Start: start,
 					Len:   l,
@@ -133,7 +208,7 @@ func (s *Set) OrdChoice(parsers ...interface{}) Parser {
 			}
 		}
 		return false, 0, nil
-	}
+	})
 }
 
 func (s *Set) NamedOrdChoice(name string, parsers ...interface{}) string {
@@ -141,13 +216,19 @@ func (s *Set) NamedOrdChoice(name string, parsers ...interface{}) string {
 	return name
 }
 
-func (s *Set) Repeat(lowerBound, upperBound int, parser interface{}) Parser {
+func (s *Set) Repeat(lowerBound, upperBound int, parser interface{}) *Pattern {
 	name := s.getNames(parser)[0]
-	return func(input *Input, start int) (bool, int, *Node) {
+	expr := s.nameExpr(name)
+	return s.record(&RepeatExpr{Min: lowerBound, Max: upperBound, Expr: expr}, func(input *Input, start int) (bool, int, *Node) {
 		index := start
 		var subs []*Node
+		input.mark(start)
+		defer input.unmark()
 		for {
+			input.marks[len(input.marks)-1] = index
+			input.progress(index)
 			ok, l, node := s.Call(name, input, index)
+			input.cover(expr, ok)
 			if ok {
 				index += l
 				subs = append(subs, node)
@@ -166,7 +247,7 @@ func (s *Set) Repeat(lowerBound, upperBound int, parser interface{}) Parser {
 			Len:   index - start,
 			Subs:  subs,
 		}
-	}
+	})
 }
 
 func (s *Set) NamedRepeat(name string, lowerBound, upperBound int, parser interface{}) string {
@@ -174,7 +255,7 @@ func (s *Set) NamedRepeat(name string, lowerBound, upperBound int, parser interf
 	return name
 }
 
-func (s *Set) OneOrMore(parser interface{}) Parser {
+func (s *Set) OneOrMore(parser interface{}) *Pattern {
 	return s.Repeat(1, -1, parser)
 }
 
@@ -183,7 +264,7 @@ func (s *Set) NamedOneOrMore(name string, parser interface{}) string {
 	return name
 }
 
-func (s *Set) ZeroOrMore(parser interface{}) Parser {
+func (s *Set) ZeroOrMore(parser interface{}) *Pattern {
 	return s.Repeat(0, -1, parser)
 }
 
@@ -192,22 +273,32 @@ func (s *Set) NamedZeroOrMore(name string, parser interface{}) string {
 	return name
 }
 
-func (s *Set) Predicate(parser interface{}) Parser {
+func (s *Set) Predicate(parser interface{}) *Pattern {
 	name := s.getNames(parser)[0]
-	return func(input *Input, start int) (bool, int, *Node) {
-		if ok, _, _ := s.Call(name, input, start); ok {
+	expr := s.nameExpr(name)
+	return s.record(&PredicateExpr{Expr: expr}, func(input *Input, start int) (bool, int, *Node) {
+		input.mark(start)
+		defer input.unmark()
+		ok, _, _ := s.Call(name, input, start)
+		input.cover(expr, ok)
+		if ok {
 			return true, 0, nil
 		}
 		return false, 0, nil
-	}
+	})
 }
 
-func (s *Set) NotPredicate(parser interface{}) Parser {
+func (s *Set) NotPredicate(parser interface{}) *Pattern {
 	name := s.getNames(parser)[0]
-	return func(input *Input, start int) (bool, int, *Node) {
-		if ok, _, _ := s.Call(name, input, start); !ok {
+	expr := s.nameExpr(name)
+	return s.record(&PredicateExpr{Not: true, Expr: expr}, func(input *Input, start int) (bool, int, *Node) {
+		input.mark(start)
+		defer input.unmark()
+		ok, _, _ := s.Call(name, input, start)
+		input.cover(expr, ok)
+		if !ok {
 			return true, 0, nil
 		}
 		return false, 0, nil
-	}
+	})
 }
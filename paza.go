@@ -1,6 +1,7 @@
 package paza
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strconv"
@@ -15,28 +16,97 @@ var (
 type Set struct {
 	parsers map[string]Parser
 	serial  uint64
+	rules   map[string]Expr
+	names   []string
+	shapes  map[string]Shape
+	// node names of anonymous parsers
+	nodeNames map[string]string
+	// node paths of anonymous parsers, and anonymous parsers by expression
+	paths     map[string]string
+	anonymous map[Expr][]string
+	// shape of anonymous parser nodes
+	anonymousShape Shape
 }
 
+// Node is a match in a parse tree.
+// Nodes of named rules have Named set, nodes of anonymous parsers are named after their structure,
+// like concat or choice, see NodeName.
+// Path identifies the anonymous parser of a node by the named rule using it and the indexes of sub expressions
+// leading to its expression, like expr/0/1, so it is kept by edits elsewhere in the grammar.
+// Path is empty for nodes of named rules and nodes made inside parsers, like operator nodes.
 type Node struct {
 	Name  string
+	Named bool
+	Path  string
 	Start int
 	Len   int
 	Subs  []*Node
 }
 
+// Parser matches at start of input.
 type Parser func(input *Input, start int) (ok bool, n int, node *Node)
 
+// Pattern is a parser built by a combinator, with the expression it matches.
+// Parsers not built by combinators are described by a CustomExpr.
+type Pattern struct {
+	Expr   Expr
+	Parser Parser
+}
+
 type stackEntry struct {
 	parser string
 	start  int
 	ok     bool
 	length int
 	node   *Node
+	used   bool
+	id     int
+	active bool
+	reach  int
+}
+
+type memoKey struct {
+	parser string
+	start  int
+}
+
+type memoEntry struct {
+	ok     bool
+	length int
+	node   *Node
+	reach  int
+	// failures recorded by the call, with the fail stack below it
+	fail failState
 }
 
 type Input struct {
-	Text  []byte
-	stack []stackEntry
+	Text      []byte
+	stack     []stackEntry
+	involved  int
+	entries   int
+	marks     []int
+	cut       bool
+	cuts      int
+	memo      map[memoKey]memoEntry
+	memoLimit int
+	reach     int
+	calls     []string
+	failState
+	recovered   map[*Node]*ParseError
+	Limits      Limits
+	Tracer      Tracer
+	Coverage    *Coverage
+	InvalidUTF8 UTF8Policy
+	ctx         context.Context
+	numCalls    int
+	err         error
+	values      map[*Node]interface{}
+	reader      io.Reader
+	readErr     error
+	base        int
+	keep        int
+	lines       int
+	column      int
 }
 
 func NewInput(text []byte) *Input {
@@ -45,14 +115,53 @@ func NewInput(text []byte) *Input {
 	}
 }
 
+// EnableMemo turns on packrat memoization of completed rule results.
+// limit bounds the number of cached entries, the table is cleared when it is reached.
+// limit <= 0 means no bound.
+func (i *Input) EnableMemo(limit int) {
+	i.memo = make(map[memoKey]memoEntry)
+	i.memoLimit = limit
+}
+
+func (i *Input) memoize(key memoKey, entry memoEntry) {
+	if i.memoLimit > 0 && len(i.memo) >= i.memoLimit {
+		i.memo = make(map[memoKey]memoEntry)
+	}
+	i.memo[key] = entry
+}
+
 func NewSet() *Set {
 	return &Set{
-		parsers: make(map[string]Parser),
+		parsers:   make(map[string]Parser),
+		rules:     make(map[string]Expr),
+		nodeNames: make(map[string]string),
+		paths:     make(map[string]string),
+		anonymous: make(map[Expr][]string),
 	}
 }
 
-func (s *Set) Add(name string, parser Parser) {
-	s.parsers[name] = parser
+// Add adds a *Pattern or a Parser as the rule name.
+func (s *Set) Add(name string, parser interface{}) {
+	if _, ok := s.parsers[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	switch parser := parser.(type) {
+	case *Pattern:
+		s.parsers[name] = parser.Parser
+	case Parser:
+		s.parsers[name] = parser
+	case func(*Input, int) (bool, int, *Node):
+		s.parsers[name] = parser
+	default:
+		panic(fmt.Sprintf("unknown parser type: %T", parser))
+	}
+	s.rules[name] = s.exprOf(parser)
+	if isAnonymous(name) {
+		s.nodeNames[name] = NodeName(s.rules[name])
+		s.anonymous[s.rules[name]] = append(s.anonymous[s.rules[name]], name)
+	} else {
+		s.setPaths(name, s.rules[name])
+	}
 }
 
 func (s *Set) Call(name string, input *Input, start int) (retOk bool, retLen int, retNode *Node) {
@@ -61,38 +170,156 @@ func (s *Set) Call(name string, input *Input, start int) (retOk bool, retLen int
 		panic("parser not found: " + name)
 	}
 
-	defer func() {
-		if retNode != nil {
-			retNode.Name = name
+	if !input.enter(name, start) {
+		return false, 0, nil
+	}
+	if input.reader != nil && len(input.calls) == 0 {
+		input.release(start)
+		for node := range input.values {
+			if node.Start < start {
+				delete(input.values, node)
+			}
 		}
-	}()
+	}
+
+	if input.Coverage != nil && !isAnonymous(name) {
+		defer func() {
+			input.cover(s.rules[name], retOk)
+		}()
+	}
+
+	cached, seed := false, false
+	if input.Tracer != nil {
+		depth := len(input.calls)
+		input.Tracer.Enter(TraceEvent{Rule: name, Start: start, Depth: depth})
+		defer func() {
+			input.Tracer.Exit(TraceEvent{
+				Rule:  name,
+				Start: start,
+				Depth: depth,
+				Ok:    retOk,
+				Len:   retLen,
+				Memo:  cached,
+				Seed:  seed,
+			})
+		}()
+	}
 
 	// search stack
 	for i := len(input.stack) - 1; i >= 0; i-- {
 		mem := input.stack[i]
 		if mem.parser == name && mem.start == start { // found
+			input.stack[i].used = true
+			if mem.id < input.involved {
+				input.involved = mem.id
+			}
+			input.examine(mem.reach)
+			seed = true
+			return mem.ok, mem.length, mem.node
+		}
+	}
+	// search memo
+	key := memoKey{name, start}
+	if input.memo != nil {
+		if mem, ok := input.memo[key]; ok {
+			input.examine(mem.reach)
+			input.replayFail(mem.fail)
+			cached = true
 			return mem.ok, mem.length, mem.node
 		}
 	}
 	// not found, append a new entry
+	depth := len(input.calls)
+	input.calls = append(input.calls, name)
+	defer func() {
+		input.calls = input.calls[:len(input.calls)-1]
+	}()
+	index := len(input.stack)
+	input.entries++
+	id := input.entries
 	input.stack = append(input.stack, stackEntry{
 		parser: name,
 		start:  start,
 		ok:     false,
 		length: 0,
 		node:   nil,
+		id:     id,
+		active: true,
 	})
+	// track the lowest stack entry used by this call
+	outerInvolved := input.involved
+	input.involved = id
+	// track the extent of text examined by this call
+	outerReach := input.reach
+	input.reach = start
+	// record failures of this call separately for memo hits to replay
+	var outerFail failState
+	cuts := input.cuts
+	if input.memo != nil {
+		outerFail = input.saveFail(start)
+	}
+	defer func() {
+		reach := input.reach
+		input.stack[index].active = false
+		input.stack[index].reach = reach
+		// pop the entry, a later call must not see a result of an unfinished growth
+		input.stack = input.stack[:index]
+		input.reach = outerReach
+		input.examine(reach)
+		var fail failState
+		if input.memo != nil {
+			fail = input.ownFail(depth)
+			// failures before a cut are not reported
+			if input.cuts == cuts {
+				input.restoreFail(outerFail)
+			}
+		}
+		involved := input.involved
+		if involved >= id { // not depending on outer seeds
+			// a call leaving a cut in effect is not memoized, a memo hit would not commit
+			if input.memo != nil && input.err == nil && !(input.cut && input.cuts != cuts) {
+				input.memoize(key, memoEntry{retOk, retLen, retNode, reach, fail})
+			}
+			involved = outerInvolved
+		} else if outerInvolved < involved {
+			involved = outerInvolved
+		}
+		input.involved = involved
+	}()
 	// find the right bound
 	lastOk := false
 	lastLen := 0
 	var lastNode *Node
-	stackSize := len(input.stack) // save stack size
-	for {
+	for iterations := 1; ; iterations++ {
+		if input.Limits.MaxGrowth > 0 && iterations > input.Limits.MaxGrowth+1 {
+			input.abort(&LimitError{"growth", input.Limits.MaxGrowth, name, start})
+			return false, 0, nil
+		}
 		ok, l, node := parser(input, start)
-		input.stack = input.stack[:stackSize] // unwind stack
+		if node != nil {
+			if nodeName, ok := s.nodeNames[name]; ok {
+				node.Name = nodeName
+				node.Path = s.paths[name]
+			} else {
+				node.Name = name
+				node.Named = true
+			}
+			node = s.shape(input, name, node)
+		}
+		// entries below may be released by a cut
+		if index >= len(input.stack) {
+			index = len(input.stack) - 1
+		}
+		for input.stack[index].id != id {
+			index--
+		}
+		input.stack = input.stack[:index+1] // unwind stack
 		if !ok {
 			return false, 0, nil
 		}
+		if !input.stack[index].used { // not left recursive, no need to grow
+			return ok, l, node
+		}
 		if l < lastLen { // over bound
 			return lastOk, lastLen, lastNode
 		} else if l == lastLen { // not extending
@@ -101,16 +328,20 @@ func (s *Set) Call(name string, input *Input, start int) (retOk bool, retLen int
 		lastOk = ok
 		lastLen = l
 		lastNode = node
-		// update stack
-		for i := len(input.stack) - 1; i >= 0; i-- {
-			e := input.stack[i]
-			if e.parser == name && e.start == start {
-				input.stack[i].ok = ok
-				input.stack[i].length = l
-				input.stack[i].node = node
-				break
-			}
+		if input.Tracer != nil {
+			input.Tracer.SeedGrow(TraceEvent{
+				Rule:      name,
+				Start:     start,
+				Depth:     len(input.calls) - 1,
+				Ok:        ok,
+				Len:       l,
+				Iteration: iterations,
+			})
 		}
+		// update stack
+		input.stack[index].ok = ok
+		input.stack[index].length = l
+		input.stack[index].node = node
 	}
 
 }
@@ -120,7 +351,7 @@ func (s *Set) getNames(parsers ...interface{}) (ret []string) {
 		switch parser := parser.(type) {
 		case string:
 			ret = append(ret, parser)
-		case Parser:
+		case *Pattern, Parser, func(*Input, int) (bool, int, *Node):
 			name := "__parser__" + strconv.Itoa(int(atomic.AddUint64(&s.serial, 1)))
 			s.Add(name, parser)
 			ret = append(ret, name)
@@ -134,7 +365,7 @@ func (s *Set) getNames(parsers ...interface{}) (ret []string) {
 func (n *Node) dump(writer io.Writer, input *Input, level int) {
 	start := n.Start
 	end := n.Start + n.Len
-	fmt.Fprintf(writer, "%s%q %s %d-%d\n", strings.Repeat("  ", level), input.Text[start:end], n.Name, start, end)
+	fmt.Fprintf(writer, "%s%q %s %d-%d\n", strings.Repeat("  ", level), input.Slice(start, end), n.Name, start, end)
 	for _, sub := range n.Subs {
 		sub.dump(writer, input, level+1)
 	}
@@ -145,7 +376,10 @@ func (n *Node) Dump(writer io.Writer, input *Input) {
 }
 
 func (n *Node) Equal(n2 *Node) bool {
-	if n.Name != n2.Name {
+	if n == nil || n2 == nil {
+		return n == n2
+	}
+	if n.Name != n2.Name || n.Named != n2.Named || n.Path != n2.Path {
 		return false
 	}
 	if n.Start != n2.Start {
@@ -216,14 +216,66 @@ func TestPanic(t *testing.T) {
 	set.Add("rune", set.Rune('a'))
 	func() {
 		defer func() {
-			if p := recover(); p == nil || p.(string) != "utf8 decode error" {
-				t.Fatal("should panic")
+			if p := recover(); p != nil {
+				t.Fatal("should not panic")
 			}
 		}()
-		set.Call("rune", NewInput([]byte("白")[1:]), 0)
+		input := NewInput([]byte("白")[1:])
+		if ok, _, _ := set.Call("rune", input, 0); ok {
+			t.Fatal("should fail")
+		}
+		if err, ok := input.Err().(*UTF8Error); !ok || err.Offset != 0 {
+			t.Fatalf("got %v", input.Err())
+		}
 	}()
 }
 
+func TestInvalidUTF8(t *testing.T) {
+	set := NewSet()
+	set.Add("a", set.OrdChoice(
+		set.Concat(set.Rune('a'), set.Rune('b')),
+		set.Concat(set.Rune('a'), set.Rune('\uFFFD')),
+		set.Concat(set.Rune('a'), set.AnyRune(), set.Regex(`.*`)),
+	))
+	text := []byte("a\xffc")
+	cases := []struct {
+		policy UTF8Policy
+		ok     bool
+		length int
+	}{
+		{InvalidUTF8Error, false, 0},
+		{InvalidUTF8Fail, false, 0},
+		{InvalidUTF8Replace, true, 2},
+	}
+	for _, c := range cases {
+		input := NewInput(text)
+		input.InvalidUTF8 = c.policy
+		ok, l, _ := set.Call("a", input, 0)
+		if ok != c.ok || l != c.length {
+			t.Fatalf("%v: %v %d", c.policy, ok, l)
+		}
+	}
+
+	input := NewInput(text)
+	_, err := set.Parse("a", input)
+	if err == nil || err.Error() != "invalid UTF-8 at offset 1" {
+		t.Fatalf("got %v", err)
+	}
+
+	input = NewInput(text)
+	input.InvalidUTF8 = InvalidUTF8Fail
+	_, err = set.Parse("a", input)
+	if err == nil || err.Error() != "parse error at offset 1: expected 'b', '�' or any character (in a)" {
+		t.Fatalf("got %v", err)
+	}
+
+	// encoded U+FFFD is valid
+	input = NewInput([]byte("a\uFFFD"))
+	if ok, l, _ := set.Call("a", input, 0); !ok || l != 4 {
+		t.Fatalf("%v %d", ok, l)
+	}
+}
+
 func TestByteIn(t *testing.T) {
 	set := NewSet()
 	set.Add("foo", set.ByteIn([]byte("qwerty")))
@@ -283,10 +335,10 @@ func TestZeroOrMore(t *testing.T) {
 func TestDump(t *testing.T) {
 	buf := new(bytes.Buffer)
 	input := NewInput([]byte("foo"))
-	node := &Node{"name", 0, 3, []*Node{
-		{"sub1", 0, 1, nil},
-		{"sub2", 1, 1, nil},
-		{"sub3", 2, 1, nil},
+	node := &Node{"name", true, "", 0, 3, []*Node{
+		{"sub1", true, "", 0, 1, nil},
+		{"sub2", true, "", 1, 1, nil},
+		{"sub3", true, "", 2, 1, nil},
 	}}
 	node.Dump(buf, input)
 	if !bytes.Equal(buf.Bytes(), []byte(`"foo" name 0-3
@@ -299,29 +351,29 @@ func TestDump(t *testing.T) {
 }
 
 func TestEqual(t *testing.T) {
-	node := &Node{"name", 0, 3, []*Node{
-		{"sub1", 0, 1, nil},
-		{"sub2", 1, 1, nil},
-		{"sub3", 2, 1, nil},
+	node := &Node{"name", true, "", 0, 3, []*Node{
+		{"sub1", true, "", 0, 1, nil},
+		{"sub2", true, "", 1, 1, nil},
+		{"sub3", true, "", 2, 1, nil},
 	}}
-	if node.Equal(&Node{"foo", 0, 3, nil}) {
+	if node.Equal(&Node{"foo", true, "", 0, 3, nil}) {
 		t.Fatal("name")
 	}
-	if node.Equal(&Node{"name", 1, 3, nil}) {
+	if node.Equal(&Node{"name", true, "", 1, 3, nil}) {
 		t.Fatal("start")
 	}
-	if node.Equal(&Node{"name", 0, 2, nil}) {
+	if node.Equal(&Node{"name", true, "", 0, 2, nil}) {
 		t.Fatal("len")
 	}
-	if node.Equal(&Node{"name", 0, 3, []*Node{
-		{"sub1", 2, 1, nil},
+	if node.Equal(&Node{"name", true, "", 0, 3, []*Node{
+		{"sub1", true, "", 2, 1, nil},
 	}}) {
 		t.Fatal("sub len")
 	}
-	if node.Equal(&Node{"name", 0, 3, []*Node{
-		{"sub1", 0, 1, nil},
-		{"sub2", 1, 1, nil},
-		{"sub8", 2, 1, nil},
+	if node.Equal(&Node{"name", true, "", 0, 3, []*Node{
+		{"sub1", true, "", 0, 1, nil},
+		{"sub2", true, "", 1, 1, nil},
+		{"sub8", true, "", 2, 1, nil},
 	}}) {
 		t.Fatal("sub")
 	}
@@ -416,3 +468,23 @@ func TestPredicate(t *testing.T) {
 	}
 	test(t, set, cases)
 }
+
+func TestCallTwice(t *testing.T) {
+	set := calcSet()
+	input := NewInput([]byte("(1+2)*3"))
+	for i := 0; i < 2; i++ {
+		if ok, l, _ := set.Call("factor", input, 0); !ok || l != 5 {
+			t.Fatalf("%d: got %v %d", i, ok, l)
+		}
+	}
+	input = NewInput([]byte("12"))
+	for i := 0; i < 2; i++ {
+		node, err := set.Parse("digit", input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Len != 2 {
+			t.Fatal("bad node")
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package paza
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+/*
+PEG grammar text syntax:
+
+	# comment
+	rule <- alt / alt    ordered choice
+	a b c                sequence
+	e* e+ e? e{n,m}      repetition
+	&e !e                predicates
+	( e )                grouping
+	name                 rule reference
+	'lit' "lit"          literal, Go escapes allowed
+	[a-z]                character class
+	`[0-9]+`             regular expression
+	.                    any character
+	^                    cut, commits the sequence
+*/
+
+type GrammarError struct {
+	Position
+	Msg string
+}
+
+func (e *GrammarError) Error() string {
+	return fmt.Sprintf("grammar %d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+type pegParser struct {
+	input *Input
+	text  []byte
+	pos   int
+}
+
+// ParseGrammar parses a PEG grammar text.
+func ParseGrammar(text []byte) (*Grammar, error) {
+	p := &pegParser{
+		input: NewInput(text),
+		text:  text,
+	}
+	return p.grammar()
+}
+
+// LoadGrammar parses a PEG grammar text and adds its rules to the set.
+func (s *Set) LoadGrammar(text []byte) error {
+	g, err := ParseGrammar(text)
+	if err != nil {
+		return err
+	}
+	s.AddGrammar(g)
+	return nil
+}
+
+func (p *pegParser) errorf(pos int, format string, args ...interface{}) error {
+	return &GrammarError{
+		Position: p.input.Position(pos),
+		Msg:      fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *pegParser) skip() {
+	for p.pos < len(p.text) {
+		switch p.text[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *pegParser) peek(s string) bool {
+	return len(p.text)-p.pos >= len(s) && string(p.text[p.pos:p.pos+len(s)]) == s
+}
+
+func (p *pegParser) eat(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		p.skip()
+		return true
+	}
+	return false
+}
+
+func isIdentStart(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isIdent(b byte) bool {
+	return isIdentStart(b) || b == '-' || b >= '0' && b <= '9'
+}
+
+func (p *pegParser) ident() string {
+	if p.pos >= len(p.text) || !isIdentStart(p.text[p.pos]) {
+		return ""
+	}
+	start := p.pos
+	for p.pos < len(p.text) && isIdent(p.text[p.pos]) {
+		p.pos++
+	}
+	name := string(p.text[start:p.pos])
+	p.skip()
+	return name
+}
+
+// atRuleStart reports whether an identifier followed by '<-' begins at the current position.
+func (p *pegParser) atRuleStart() bool {
+	pos := p.pos
+	defer func() {
+		p.pos = pos
+	}()
+	return p.ident() != "" && p.peek("<-")
+}
+
+func (p *pegParser) grammar() (*Grammar, error) {
+	g := new(Grammar)
+	defined := make(map[string]bool)
+	p.skip()
+	for p.pos < len(p.text) {
+		pos := p.pos
+		name := p.ident()
+		if name == "" {
+			return nil, p.errorf(p.pos, "expected rule name")
+		}
+		if defined[name] {
+			return nil, p.errorf(pos, "duplicated rule: %s", name)
+		}
+		defined[name] = true
+		if !p.eat("<-") {
+			return nil, p.errorf(p.pos, "expected '<-'")
+		}
+		expr, err := p.choice()
+		if err != nil {
+			return nil, err
+		}
+		g.Rules = append(g.Rules, &Rule{
+			Name: name,
+			Expr: expr,
+		})
+	}
+	if len(g.Rules) == 0 {
+		return nil, p.errorf(p.pos, "no rules")
+	}
+	return g, nil
+}
+
+func (p *pegParser) choice() (Expr, error) {
+	var exprs []Expr
+	for {
+		expr, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.eat("/") {
+			break
+		}
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &ChoiceExpr{Exprs: exprs}, nil
+}
+
+func (p *pegParser) sequence() (Expr, error) {
+	var exprs []Expr
+	for p.pos < len(p.text) && !p.peek("/") && !p.peek(")") && !p.atRuleStart() {
+		expr, err := p.prefixed()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	switch len(exprs) {
+	case 0:
+		return nil, p.errorf(p.pos, "expected expression")
+	case 1:
+		return exprs[0], nil
+	}
+	return &ConcatExpr{Exprs: exprs}, nil
+}
+
+func (p *pegParser) prefixed() (Expr, error) {
+	if p.eat("&") {
+		expr, err := p.suffixed()
+		if err != nil {
+			return nil, err
+		}
+		return &PredicateExpr{Expr: expr}, nil
+	}
+	if p.eat("!") {
+		expr, err := p.suffixed()
+		if err != nil {
+			return nil, err
+		}
+		return &PredicateExpr{Not: true, Expr: expr}, nil
+	}
+	return p.suffixed()
+}
+
+func (p *pegParser) suffixed() (Expr, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.eat("*"):
+			expr = &RepeatExpr{Min: 0, Max: -1, Expr: expr}
+		case p.eat("+"):
+			expr = &RepeatExpr{Min: 1, Max: -1, Expr: expr}
+		case p.eat("?"):
+			expr = &RepeatExpr{Min: 0, Max: 1, Expr: expr}
+		case p.peek("{"):
+			min, max, err := p.bounds()
+			if err != nil {
+				return nil, err
+			}
+			expr = &RepeatExpr{Min: min, Max: max, Expr: expr}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+// bounds parses {n}, {n,} or {n,m}
+func (p *pegParser) bounds() (min, max int, err error) {
+	start := p.pos
+	p.eat("{")
+	min, ok := p.number()
+	if !ok {
+		return 0, 0, p.errorf(p.pos, "expected number")
+	}
+	max = min
+	if p.eat(",") {
+		max = -1
+		if n, ok := p.number(); ok {
+			max = n
+		}
+	}
+	if !p.eat("}") {
+		return 0, 0, p.errorf(p.pos, "expected '}'")
+	}
+	if max >= 0 && max < min || max == 0 {
+		return 0, 0, p.errorf(start, "bad repetition bounds")
+	}
+	return min, max, nil
+}
+
+func (p *pegParser) number() (int, bool) {
+	start := p.pos
+	for p.pos < len(p.text) && p.text[p.pos] >= '0' && p.text[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(string(p.text[start:p.pos]))
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	p.skip()
+	return n, true
+}
+
+func (p *pegParser) primary() (Expr, error) {
+	if p.pos >= len(p.text) {
+		return nil, p.errorf(p.pos, "expected expression")
+	}
+	start := p.pos
+	switch c := p.text[p.pos]; {
+	case isIdentStart(c):
+		return &RefExpr{Name: p.ident()}, nil
+	case c == '(':
+		p.eat("(")
+		expr, err := p.choice()
+		if err != nil {
+			return nil, err
+		}
+		if !p.eat(")") {
+			return nil, p.errorf(p.pos, "expected ')'")
+		}
+		return expr, nil
+	case c == '\'' || c == '"':
+		return p.literal()
+	case c == '[':
+		for p.pos++; p.pos < len(p.text) && p.text[p.pos] != ']'; p.pos++ {
+			if p.text[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		if p.pos >= len(p.text) {
+			return nil, p.errorf(start, "unterminated character class")
+		}
+		p.pos++
+		return p.regex(start, string(p.text[start:p.pos]))
+	case c == '`':
+		p.pos++
+		for p.pos < len(p.text) && p.text[p.pos] != '`' {
+			p.pos++
+		}
+		if p.pos >= len(p.text) {
+			return nil, p.errorf(start, "unterminated regular expression")
+		}
+		p.pos++
+		return p.regex(start, string(p.text[start+1:p.pos-1]))
+	case c == '.':
+		p.eat(".")
+		return &AnyRuneExpr{}, nil
+	case c == '^':
+		p.eat("^")
+		return &CutExpr{}, nil
+	}
+	return nil, p.errorf(p.pos, "unexpected %q", p.text[p.pos])
+}
+
+func (p *pegParser) regex(start int, pattern string) (Expr, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, p.errorf(start, "%v", err)
+	}
+	p.skip()
+	return &RegexExpr{Pattern: pattern}, nil
+}
+
+func (p *pegParser) literal() (Expr, error) {
+	start := p.pos
+	quote := p.text[p.pos]
+	// convert to a double quoted Go string
+	buf := []byte{'"'}
+	for p.pos++; ; p.pos++ {
+		if p.pos >= len(p.text) || p.text[p.pos] == '\n' {
+			return nil, p.errorf(start, "unterminated literal")
+		}
+		c := p.text[p.pos]
+		if c == quote {
+			break
+		}
+		switch c {
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.text) {
+				return nil, p.errorf(start, "unterminated literal")
+			}
+			if p.text[p.pos] == '\'' {
+				buf = append(buf, '\'')
+			} else {
+				buf = append(buf, '\\', p.text[p.pos])
+			}
+		case '"':
+			buf = append(buf, '\\', '"')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	p.pos++
+	buf = append(buf, '"')
+	text, err := strconv.Unquote(string(buf))
+	if err != nil {
+		return nil, p.errorf(start, "bad literal: %v", err)
+	}
+	if text == "" {
+		return nil, p.errorf(start, "empty literal")
+	}
+	p.skip()
+	if r, l := utf8.DecodeRuneInString(text); l == len(text) && r != utf8.RuneError {
+		return &RuneExpr{Rune: r}, nil
+	}
+	return &LiteralExpr{Text: text}, nil
+}
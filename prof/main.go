@@ -4,7 +4,7 @@ import (
 	"os"
 	"runtime/pprof"
 
-	paza "../"
+	"github.com/reusee/paza"
 )
 
 func main() {
@@ -13,10 +13,11 @@ func main() {
 		set.Concat(
 			"expr",
 			set.ByteIn([]byte("+-*/")),
-			set.OneOrMore(set.ByteRange('a', 'z')),
+			"word",
 		),
-		set.OneOrMore(set.ByteRange('a', 'z')),
+		"word",
 	))
+	set.Add("word", set.OneOrMore(set.ByteRange('a', 'z')))
 	n := 100000
 	f, err := os.Create("profile")
 	if err != nil {
@@ -34,4 +35,23 @@ func main() {
 		}
 	}
 	pprof.StopCPUProfile()
+
+	// rule level profile
+	profiler := paza.NewProfiler()
+	for i := 0; i < n/10; i++ {
+		input := paza.NewInput([]byte("foo+bar-baz*qux/quux"))
+		input.Tracer = profiler
+		set.Call("expr", input, 0)
+	}
+	if err := profiler.Report(os.Stdout); err != nil {
+		panic(err)
+	}
+	f, err = os.Create("rules.profile")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if err := profiler.WriteProfile(f); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,255 @@
+package paza
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// RuleProfile is the profile of a rule.
+// Self excludes the time spent in other profiled rules called by it,
+// Cum includes it, counting recursive calls once.
+type RuleProfile struct {
+	Rule     string
+	Calls    int
+	Fails    int
+	Growths  int
+	MemoHits int
+	SeedHits int
+	Self     time.Duration
+	Cum      time.Duration
+}
+
+// Profiler is a Tracer recording per rule statistics.
+// Calls of anonymous parsers are accounted to the calling rule unless Anonymous is set.
+type Profiler struct {
+	Anonymous bool
+	rules     map[string]*RuleProfile
+	active    map[string]int
+	frames    []profileFrame
+	samples   map[string]*profileSample
+	now       func() time.Time
+}
+
+type profileFrame struct {
+	rule     string
+	start    time.Time
+	children time.Duration
+}
+
+type profileSample struct {
+	stack []string
+	calls int
+	self  time.Duration
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		rules:   make(map[string]*RuleProfile),
+		active:  make(map[string]int),
+		samples: make(map[string]*profileSample),
+		now:     time.Now,
+	}
+}
+
+func (p *Profiler) skip(e TraceEvent) bool {
+	return !p.Anonymous && isAnonymous(e.Rule)
+}
+
+func (p *Profiler) rule(name string) *RuleProfile {
+	r, ok := p.rules[name]
+	if !ok {
+		r = &RuleProfile{Rule: name}
+		p.rules[name] = r
+	}
+	return r
+}
+
+func (p *Profiler) Enter(e TraceEvent) {
+	if p.skip(e) {
+		return
+	}
+	p.rule(e.Rule).Calls++
+	p.active[e.Rule]++
+	p.frames = append(p.frames, profileFrame{
+		rule:  e.Rule,
+		start: p.now(),
+	})
+}
+
+func (p *Profiler) Exit(e TraceEvent) {
+	if p.skip(e) {
+		return
+	}
+	r := p.rule(e.Rule)
+	if !e.Ok {
+		r.Fails++
+	}
+	if e.Memo {
+		r.MemoHits++
+	} else if e.Seed {
+		r.SeedHits++
+	}
+	frame := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+	cum := p.now().Sub(frame.start)
+	self := cum - frame.children
+	r.Self += self
+	p.active[e.Rule]--
+	if p.active[e.Rule] == 0 { // outermost
+		r.Cum += cum
+	}
+	if len(p.frames) > 0 {
+		p.frames[len(p.frames)-1].children += cum
+	}
+	// sample keyed by the call stack, leaf first
+	stack := []string{e.Rule}
+	for i := len(p.frames) - 1; i >= 0; i-- {
+		stack = append(stack, p.frames[i].rule)
+	}
+	key := strings.Join(stack, "\x00")
+	sample, ok := p.samples[key]
+	if !ok {
+		sample = &profileSample{stack: stack}
+		p.samples[key] = sample
+	}
+	sample.calls++
+	sample.self += self
+}
+
+func (p *Profiler) SeedGrow(e TraceEvent) {
+	if p.skip(e) {
+		return
+	}
+	p.rule(e.Rule).Growths++
+}
+
+// Rules returns the rule profiles, sorted by self time.
+func (p *Profiler) Rules() []*RuleProfile {
+	rules := make([]*RuleProfile, 0, len(p.rules))
+	for _, r := range p.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Self != rules[j].Self {
+			return rules[i].Self > rules[j].Self
+		}
+		return rules[i].Rule < rules[j].Rule
+	})
+	return rules
+}
+
+// Report writes the rule profiles as a table.
+func (p *Profiler) Report(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "rule\tcalls\tfails\tgrowths\tmemo\tseed\tself\tcum\n")
+	for _, r := range p.Rules() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%v\t%v\n",
+			r.Rule, r.Calls, r.Fails, r.Growths, r.MemoHits, r.SeedHits, r.Self, r.Cum)
+	}
+	return tw.Flush()
+}
+
+// WriteProfile writes a gzipped pprof profile with rule names as frames,
+// and calls and self time in nanoseconds as sample values.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	var strs []string
+	strIndex := make(map[string]int)
+	str := func(s string) int {
+		if i, ok := strIndex[s]; ok {
+			return i
+		}
+		strIndex[s] = len(strs)
+		strs = append(strs, s)
+		return strIndex[s]
+	}
+	str("")
+
+	var buf protoBuffer
+	for _, t := range [][2]string{{"calls", "count"}, {"self", "nanoseconds"}} {
+		var vt protoBuffer
+		vt.int(1, int64(str(t[0])))
+		vt.int(2, int64(str(t[1])))
+		buf.bytes(1, vt)
+	}
+
+	// one function and location per rule
+	ids := make(map[string]uint64)
+	var names []string
+	for name := range p.rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		ids[name] = uint64(i + 1)
+	}
+
+	var keys []string
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sample := p.samples[key]
+		var locs, values protoBuffer
+		for _, rule := range sample.stack {
+			locs.varint(ids[rule])
+		}
+		values.varint(uint64(sample.calls))
+		values.varint(uint64(sample.self.Nanoseconds()))
+		var s protoBuffer
+		s.bytes(1, locs)
+		s.bytes(2, values)
+		buf.bytes(2, s)
+	}
+
+	for _, name := range names {
+		var line, loc protoBuffer
+		line.int(1, int64(ids[name]))
+		loc.int(1, int64(ids[name]))
+		loc.bytes(4, line)
+		buf.bytes(4, loc)
+	}
+	for _, name := range names {
+		var fn protoBuffer
+		fn.int(1, int64(ids[name]))
+		fn.int(2, int64(str(name)))
+		fn.int(3, int64(str(name)))
+		buf.bytes(5, fn)
+	}
+	for _, s := range strs {
+		buf.bytes(6, protoBuffer(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(buf); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protoBuffer encodes protocol buffer fields
+type protoBuffer []byte
+
+func (b *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protoBuffer) int(field int, v int64) {
+	b.varint(uint64(field) << 3)
+	b.varint(uint64(v))
+}
+
+func (b *protoBuffer) bytes(field int, bs []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(bs)))
+	*b = append(*b, bs...)
+}
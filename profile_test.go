@@ -0,0 +1,72 @@
+package paza
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestProfiler(t *testing.T) {
+	set := NewSet()
+	set.Add("expr", set.OrdChoice(
+		set.NamedConcat("plus-expr", "expr", set.Rune('+'), "num"),
+		"num",
+	))
+	set.NamedRegex("num", `[0-9]`)
+	profiler := NewProfiler()
+	var now time.Time
+	profiler.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	input := NewInput([]byte("1+2"))
+	input.Tracer = profiler
+	set.Call("expr", input, 0)
+
+	expected := map[string]RuleProfile{
+		"expr":      {"expr", 4, 1, 2, 0, 3, 9 * time.Millisecond, 19 * time.Millisecond},
+		"plus-expr": {"plus-expr", 3, 2, 0, 0, 0, 7 * time.Millisecond, 11 * time.Millisecond},
+		"num":       {"num", 3, 0, 0, 0, 0, 3 * time.Millisecond, 3 * time.Millisecond},
+	}
+	rules := profiler.Rules()
+	if len(rules) != 3 || rules[0].Rule != "expr" {
+		t.Fatalf("bad rules")
+	}
+	for _, r := range rules {
+		if *r != expected[r.Rule] {
+			t.Fatalf("got %+v", *r)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := profiler.Report(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `rule       calls  fails  growths  memo  seed  self  cum
+expr       4      1      2        0     3     9ms   19ms
+plus-expr  3      2      0        0     0     7ms   11ms
+num        3      0      0        0     0     3ms   3ms
+` {
+		t.Fatalf("got\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := profiler.WriteProfile(buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"plus-expr", "num", "nanoseconds"} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Fatalf("%s not in profile", name)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package paza
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Query syntax, selecting named nodes, anonymous nodes are transparent:
+
+	name        nodes of the rule
+	*           any node
+	a b         b nodes inside a nodes
+	a > b       b nodes whose nearest named parent is an a node
+	b:nth(2)    b nodes that are the second b under their parent, negative counts from the last
+	*:nth(2)    second nodes under their parent
+	a, b        a or b nodes
+*/
+
+type Query struct {
+	text   string
+	groups [][]queryStep
+}
+
+type queryStep struct {
+	child bool // combinator before this step
+	name  string
+	nth   int
+}
+
+type QueryError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query offset %d: %s", e.Offset, e.Msg)
+}
+
+// CompileQuery parses a query.
+func CompileQuery(text string) (*Query, error) {
+	q := &Query{
+		text: text,
+	}
+	pos := 0
+	skip := func() bool {
+		start := pos
+		for pos < len(text) && (text[pos] == ' ' || text[pos] == '\t' || text[pos] == '\n') {
+			pos++
+		}
+		return pos > start
+	}
+	var group []queryStep
+	child := false
+	skip()
+	for {
+		if pos >= len(text) || text[pos] == ',' {
+			if len(group) == 0 || child {
+				return nil, &QueryError{pos, "expected selector"}
+			}
+			q.groups = append(q.groups, group)
+			group = nil
+			if pos >= len(text) {
+				break
+			}
+			pos++
+			skip()
+			continue
+		}
+		if text[pos] == '>' {
+			if len(group) == 0 || child {
+				return nil, &QueryError{pos, "unexpected '>'"}
+			}
+			child = true
+			pos++
+			skip()
+			continue
+		}
+		step := queryStep{child: child}
+		child = false
+		start := pos
+		if text[pos] == '*' {
+			pos++
+		} else {
+			for pos < len(text) && isQueryName(text[pos]) {
+				pos++
+			}
+			if pos == start {
+				return nil, &QueryError{pos, fmt.Sprintf("unexpected %q", text[pos])}
+			}
+		}
+		step.name = text[start:pos]
+		if strings.HasPrefix(text[pos:], ":") {
+			if !strings.HasPrefix(text[pos:], ":nth(") {
+				return nil, &QueryError{pos, "unknown pseudo selector"}
+			}
+			pos += len(":nth(")
+			end := strings.IndexByte(text[pos:], ')')
+			if end < 0 {
+				return nil, &QueryError{pos, "expected ')'"}
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(text[pos : pos+end]))
+			if err != nil || n == 0 {
+				return nil, &QueryError{pos, "bad index"}
+			}
+			step.nth = n
+			pos += end + 1
+		}
+		group = append(group, step)
+		if !skip() && pos < len(text) && text[pos] != ',' && text[pos] != '>' {
+			return nil, &QueryError{pos, fmt.Sprintf("unexpected %q", text[pos])}
+		}
+	}
+	return q, nil
+}
+
+// MustCompileQuery is like CompileQuery but panics on error.
+func MustCompileQuery(text string) *Query {
+	q, err := CompileQuery(text)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func isQueryName(b byte) bool {
+	return b == '_' || b == '-' || b == '.' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+func (q *Query) String() string {
+	return q.text
+}
+
+// position of a named node among the named sub nodes of its named parent
+type queryInfo struct {
+	parent   *Node
+	index    int
+	count    int
+	indexAll int
+	countAll int
+}
+
+// Find returns nodes under node, or node itself, matching the query, in document order.
+func (q *Query) Find(node *Node) []*Node {
+	infos := make(map[*Node]queryInfo)
+	var order []*Node
+	var index func(node *Node)
+	index = func(node *Node) {
+		order = append(order, node)
+		subs := namedSubs(node, nil)
+		counts := make(map[string]int)
+		for _, sub := range subs {
+			counts[sub.Name]++
+		}
+		seen := make(map[string]int)
+		for i, sub := range subs {
+			seen[sub.Name]++
+			infos[sub] = queryInfo{node, seen[sub.Name], counts[sub.Name], i + 1, len(subs)}
+			index(sub)
+		}
+	}
+	index(node)
+
+	matched := make(map[*Node]bool)
+	for _, group := range q.groups {
+		var current []*Node
+		for _, n := range order {
+			if group[0].match(n, infos) {
+				current = append(current, n)
+			}
+		}
+		for _, step := range group[1:] {
+			set := make(map[*Node]bool)
+			for _, n := range current {
+				set[n] = true
+			}
+			current = current[:0:0]
+			for _, n := range order {
+				if !step.match(n, infos) {
+					continue
+				}
+				// check the axis
+				for p := infos[n].parent; p != nil; p = infos[p].parent {
+					if set[p] {
+						current = append(current, n)
+						break
+					}
+					if step.child {
+						break
+					}
+				}
+			}
+		}
+		for _, n := range current {
+			matched[n] = true
+		}
+	}
+	var ret []*Node
+	for _, n := range order {
+		if matched[n] {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+func (s queryStep) match(node *Node, infos map[*Node]queryInfo) bool {
+	if s.name != "*" && node.Name != s.name {
+		return false
+	}
+	if s.nth != 0 {
+		info, ok := infos[node]
+		if !ok {
+			return s.nth == 1 || s.nth == -1
+		}
+		index, count := info.index, info.count
+		if s.name == "*" {
+			index, count = info.indexAll, info.countAll
+		}
+		if s.nth > 0 && index != s.nth || s.nth < 0 && count+1+s.nth != index {
+			return false
+		}
+	}
+	return true
+}
+
+// namedSubs collects the nearest named sub nodes, descending into anonymous ones
+func namedSubs(node *Node, ret []*Node) []*Node {
+	for _, sub := range node.Subs {
+		if sub == nil {
+			continue
+		}
+		if !sub.Named {
+			ret = namedSubs(sub, ret)
+		} else {
+			ret = append(ret, sub)
+		}
+	}
+	return ret
+}
+
+// Query returns nodes under n, or n itself, matching the query text.
+func (n *Node) Query(text string) ([]*Node, error) {
+	q, err := CompileQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	return q.Find(n), nil
+}
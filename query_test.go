@@ -0,0 +1,79 @@
+package paza
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	set := calcSet()
+	set.Add("list", set.OneOrMore(set.Concat("expr", set.Rune(';'))))
+	text := []byte("(1+2)*(3-(4/5))+6;7;8*9;")
+	_, _, node := set.Call("list", NewInput(text), 0)
+	texts := func(nodes []*Node) string {
+		var parts []string
+		for _, n := range nodes {
+			parts = append(parts, fmt.Sprintf("%s:%s", n.Name, text[n.Start:n.Start+n.Len]))
+		}
+		return strings.Join(parts, " ")
+	}
+	cases := []struct {
+		query    string
+		expected string
+	}{
+		{"quoted > expr digit", "digit:1 digit:2 digit:3 digit:4 digit:5"},
+		{"list > expr > term digit", "digit:7 digit:8 digit:9"},
+		{"quoted quoted digit", "digit:4 digit:5"},
+		{"quoted>expr>*", "plus-expr:1+2 minus-expr:3-(4/5) term:4/5"},
+		{"expr:nth(2)", "expr:7"},
+		{"list > expr:nth(-1)", "expr:8*9"},
+		{"mul-expr > *:nth(1)", "term:(1+2) term:8"},
+		{"mul-expr > *:nth(-1) > digit", "digit:9"},
+		{"div-op, mul-op", "mul-op:* div-op:/ mul-op:*"},
+		{"list", "list:" + string(text)},
+		{"foo", ""},
+	}
+	for _, c := range cases {
+		nodes, err := node.Query(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := texts(nodes); got != c.expected {
+			t.Fatalf("%q: got %s", c.query, got)
+		}
+	}
+
+	q := MustCompileQuery("digit")
+	if n := len(q.Find(node)); n != 9 {
+		t.Fatalf("got %d", n)
+	}
+	if q.String() != "digit" {
+		t.Fatal("bad string")
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{"", "query offset 0: expected selector"},
+		{"a >", "query offset 3: expected selector"},
+		{"> a", "query offset 0: unexpected '>'"},
+		{"a > > b", "query offset 4: unexpected '>'"},
+		{"a,", "query offset 2: expected selector"},
+		{"a:first", "query offset 1: unknown pseudo selector"},
+		{"a:nth(x)", "query offset 6: bad index"},
+		{"a:nth(0)", "query offset 6: bad index"},
+		{"a:nth(1", "query offset 6: expected ')'"},
+		{"a[b]", "query offset 1: unexpected '['"},
+		{"a $", "query offset 2: unexpected '$'"},
+	}
+	for _, c := range cases {
+		_, err := CompileQuery(c.query)
+		if err == nil || err.Error() != c.err {
+			t.Fatalf("%q: got %v", c.query, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package paza
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrorNode is the name of nodes covering input skipped by Recover.
+const ErrorNode = "!error"
+
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Recover matches parser, or on failure skips input up to and including the first match of sync.
+// The skipped span is covered by an ErrorNode node, whose error is returned by Input.Errors.
+// A failure after a Cut inside parser is recovered too.
+// Recover fails if parser fails and there is nothing to skip.
+func (s *Set) Recover(parser interface{}, sync interface{}) *Pattern {
+	names := s.getNames(parser, sync)
+	expr := &RecoverExpr{Expr: s.nameExpr(names[0]), Sync: s.nameExpr(names[1])}
+	return s.record(expr, func(input *Input, start int) (bool, int, *Node) {
+		saved := input.saveFail(start)
+		input.mark(start)
+		defer input.unmark()
+		ok, l, node := s.Call(names[0], input, start)
+		input.cover(expr.Expr, ok)
+		if ok {
+			input.restoreFail(saved)
+			return ok, l, &Node{
+				Start: start,
+				Len:   l,
+				Subs:  []*Node{node},
+			}
+		}
+		err := input.parseError()
+		if input.err != nil {
+			cutErr, ok := input.err.(*ParseError)
+			if !ok {
+				return false, 0, nil
+			}
+			err = cutErr
+			input.err = nil
+		}
+		// skip to the sync point
+		end := start
+		for !input.atEnd(end) {
+			ok, l, _ := s.Call(names[1], input, end)
+			input.cover(expr.Sync, ok)
+			if ok {
+				end += l
+				break
+			}
+			if input.err != nil {
+				return false, 0, nil
+			}
+			_, l = utf8.DecodeRune(input.rest(end, utf8.UTFMax))
+			end += l
+		}
+		if end == start {
+			input.restoreFail(saved)
+			return false, 0, nil
+		}
+		input.failState = saved
+		errNode := &Node{
+			Name:  ErrorNode,
+			Named: true,
+			Start: start,
+			Len:   end - start,
+		}
+		if input.recovered == nil {
+			input.recovered = make(map[*Node]*ParseError)
+		}
+		input.recovered[errNode] = err
+		return true, end - start, &Node{
+			Start: start,
+			Len:   end - start,
+			Subs:  []*Node{errNode},
+		}
+	})
+}
+
+func (s *Set) NamedRecover(name string, parser interface{}, sync interface{}) string {
+	s.Add(name, s.Recover(parser, sync))
+	return name
+}
+
+// Errors returns the errors of all error nodes in the tree, in input order.
+func (i *Input) Errors(node *Node) (errs ErrorList) {
+	if node == nil {
+		return nil
+	}
+	if err, ok := i.recovered[node]; ok {
+		errs = append(errs, err)
+	}
+	for _, sub := range node.Subs {
+		errs = append(errs, i.Errors(sub)...)
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package paza
+
+import "testing"
+
+func TestRecover(t *testing.T) {
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(`
+stmt <- 'if' ^ ' ' ident ';' / ident ';'
+ident <- [a-z]+
+`)); err != nil {
+		t.Fatal(err)
+	}
+	set.Add("file", set.ZeroOrMore(set.Recover("stmt", set.Rune(';'))))
+
+	input := NewInput([]byte("foo;if 1;bar;baz"))
+	node, err := set.Parse("file", input)
+	if node == nil || node.Len != 16 || len(node.Subs) != 4 {
+		t.Fatalf("bad node")
+	}
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("got %v", err)
+	}
+	if errs[0].Error() != "parse error at offset 7: expected [a-z] (in file > stmt > ident)" {
+		t.Fatalf("got %v", errs[0])
+	}
+	if errs[1].Error() != "parse error at offset 16: expected [a-z] or ';' (in file > stmt > ident)" {
+		t.Fatalf("got %v", errs[1])
+	}
+	if err.Error() != "parse error at offset 7: expected [a-z] (in file > stmt > ident) (and 1 more errors)" {
+		t.Fatalf("got %v", err)
+	}
+	for i, span := range [][2]int{{4, 5}, {13, 3}} {
+		sub := node.Subs[[]int{1, 3}[i]].Subs[0]
+		if sub.Name != ErrorNode || sub.Start != span[0] || sub.Len != span[1] {
+			t.Fatalf("bad error node %d: %s %d %d", i, sub.Name, sub.Start, sub.Len)
+		}
+	}
+
+	node, err = set.Parse("file", NewInput([]byte("foo;bar;")))
+	if err != nil || node.Len != 8 {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRecoverBacktrack(t *testing.T) {
+	set := NewSet()
+	set.Add("foo", set.OrdChoice(
+		set.Concat(set.Recover(set.Rune('a'), set.Rune(';')), set.Rune('x')),
+		set.Literal("b;y"),
+	))
+	input := NewInput([]byte("b;y"))
+	node, err := set.Parse("foo", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(input.Errors(node)) != 0 {
+		t.Fatal("should not report errors of abandoned alternatives")
+	}
+
+	// nothing to skip
+	set.Add("bar", set.Concat(set.Recover(set.Rune('a'), set.Rune(';')), set.Rune('b')))
+	_, err = set.Parse("bar", NewInput([]byte("")))
+	if err.Error() != "parse error at offset 0: expected 'a' (in bar)" {
+		t.Fatalf("got %v", err)
+	}
+
+	// limits are not recovered
+	input = NewInput([]byte("aaaa;"))
+	input.Limits.MaxCalls = 3
+	set.Add("baz", set.Recover(set.OneOrMore(set.Rune('a')), set.Rune(';')))
+	if _, err := set.Parse("baz", input); err == nil {
+		t.Fatal("should fail")
+	} else if _, ok := err.(*LimitError); !ok {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package paza
+
+// Shape controls how the node of a rule appears in parse trees.
+type Shape int
+
+const (
+	// Keep keeps the node as it is
+	Keep Shape = iota
+	// Hidden drops the node from the Subs of its parent
+	Hidden
+	// Inline splices the Subs of the node into the Subs of its parent
+	Inline
+	// Token keeps the node but drops its Subs, except the nearest ones holding action values
+	Token
+	// Collapse replaces a node having a single sub node with that sub node
+	Collapse
+)
+
+// Shape sets the shape of nodes of the named rule.
+// Shapes are applied to the node of each match after the parser of the rule built it,
+// so removed nodes are not kept in trees or memo tables, but they are still allocated while parsing.
+// A rule node returned by a top level Call is never hidden or inlined.
+// Nodes holding action values are never removed, so their values stay available.
+func (s *Set) Shape(name string, shape Shape) {
+	if s.shapes == nil {
+		s.shapes = make(map[string]Shape)
+	}
+	s.shapes[name] = shape
+}
+
+// ShapeAnonymous sets the shape of nodes of anonymous parsers, for those without a shape set by Shape.
+// Inline removes all anonymous wrapper nodes from trees, leaving only named rule nodes.
+func (s *Set) ShapeAnonymous(shape Shape) {
+	s.anonymousShape = shape
+}
+
+func (s *Set) shapeOf(name string) Shape {
+	if shape, ok := s.shapes[name]; ok {
+		return shape
+	}
+	if isAnonymous(name) {
+		return s.anonymousShape
+	}
+	return Keep
+}
+
+func (s *Set) nodeShape(node *Node) Shape {
+	if !node.Named {
+		return s.anonymousShape
+	}
+	return s.shapes[node.Name]
+}
+
+// shape applies shapes to the node of a match of the rule and its sub nodes
+func (s *Set) shape(input *Input, name string, node *Node) *Node {
+	if node == nil || len(s.shapes) == 0 && s.anonymousShape == Keep {
+		return node
+	}
+	// hide or inline sub nodes
+	var subs []*Node
+	for i, sub := range node.Subs {
+		shape := Keep
+		if sub != nil && !input.hasValue(sub) {
+			shape = s.nodeShape(sub)
+		}
+		if shape != Hidden && shape != Inline {
+			if subs != nil {
+				subs = append(subs, sub)
+			}
+			continue
+		}
+		if subs == nil {
+			subs = make([]*Node, i, len(node.Subs)+len(sub.Subs))
+			copy(subs, node.Subs)
+		}
+		if shape == Inline {
+			subs = append(subs, sub.Subs...)
+		}
+	}
+	if subs != nil {
+		node.Subs = subs
+	}
+	switch s.shapeOf(name) {
+	case Token:
+		node.Subs = input.valueNodes(node, nil)
+	case Collapse:
+		if input.hasValue(node) {
+			break
+		}
+		var single *Node
+		for _, sub := range node.Subs {
+			if sub == nil {
+				continue
+			}
+			if single != nil {
+				return node
+			}
+			single = sub
+		}
+		if single != nil {
+			return single
+		}
+	}
+	return node
+}
+
+// valueNodes collects the nearest sub nodes holding values
+func (i *Input) valueNodes(node *Node, ret []*Node) []*Node {
+	for _, sub := range node.Subs {
+		if sub == nil {
+			continue
+		}
+		if i.hasValue(sub) {
+			ret = append(ret, sub)
+		} else {
+			ret = i.valueNodes(sub, ret)
+		}
+	}
+	return ret
+}
+
+func (i *Input) hasValue(node *Node) bool {
+	_, ok := i.values[node]
+	return ok
+}
@@ -0,0 +1,120 @@
+package paza
+
+import "testing"
+
+func TestShape(t *testing.T) {
+	cases := []struct {
+		text     string
+		shape    func(set *Set)
+		expected *Node
+	}{
+		{"1", func(set *Set) {
+			set.Shape("expr", Collapse)
+			set.Shape("term", Collapse)
+			set.Shape("factor", Collapse)
+		}, &Node{"digit", true, "", 0, 1, nil}},
+		{"1+(2)", func(set *Set) {
+			set.Shape("term", Inline)
+			set.Shape("factor", Inline)
+			set.Shape("plus-op", Hidden)
+			set.Shape("quoted", Token)
+		}, &Node{"expr", true, "", 0, 5, []*Node{
+			{"plus-expr", true, "", 0, 5, []*Node{
+				{"expr", true, "", 0, 1, []*Node{
+					{"digit", true, "", 0, 1, nil},
+				}},
+				{"quoted", true, "", 2, 3, nil},
+			}},
+		}}},
+		{"1*2", func(set *Set) {
+			set.Shape("expr", Collapse)
+			set.Shape("term", Collapse)
+			set.Shape("factor", Collapse)
+			set.Shape("mul-op", Hidden)
+		}, &Node{"mul-expr", true, "", 0, 3, []*Node{
+			{"digit", true, "", 0, 1, nil},
+			{"digit", true, "", 2, 1, nil},
+		}}},
+	}
+	for _, c := range cases {
+		for _, memo := range []bool{false, true} {
+			set := calcSet()
+			c.shape(set)
+			input := NewInput([]byte(c.text))
+			if memo {
+				input.EnableMemo(-1)
+			}
+			node, err := set.Parse("expr", input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !node.Equal(c.expected) {
+				t.Fatalf("%s: bad tree", c.text)
+			}
+		}
+	}
+}
+
+func TestShapeAnonymous(t *testing.T) {
+	set := NewSet()
+	set.Add("list", set.Concat(
+		set.NamedRegex("item", `[a-z]+`),
+		set.ZeroOrMore(set.Concat(set.Rune(','), "item")),
+	))
+	set.ShapeAnonymous(Inline)
+	node, err := set.Parse("list", NewInput([]byte("a,b,c")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !node.Equal(&Node{"list", true, "", 0, 5, []*Node{
+		{"item", true, "", 0, 1, nil},
+		{"item", true, "", 2, 1, nil},
+		{"item", true, "", 4, 1, nil},
+	}}) {
+		t.Fatal("bad tree")
+	}
+
+	// nodes with values are kept
+	set = NewSet()
+	set.Add("num", set.Action(set.Regex(`[0-9]+`), func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+		return string(text), nil
+	}))
+	set.Add("sum", set.Concat("num", set.Rune('+'), "num"))
+	set.ShapeAnonymous(Hidden)
+	set.Shape("num", Collapse)
+	input := NewInput([]byte("1+2"))
+	node, err = set.Parse("sum", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node.Subs) != 2 || input.Value(node.Subs[1]) != "2" {
+		t.Fatal("bad tree")
+	}
+}
+
+func TestShapeTokenValues(t *testing.T) {
+	set := NewSet()
+	set.Add("num", set.Action(set.Regex(`[0-9]+`), func(node *Node, text []byte, values []interface{}) (interface{}, error) {
+		return string(text), nil
+	}))
+	set.Add("pair", set.Concat("num", set.Rune(','), "num"))
+	set.Shape("pair", Token)
+	input := NewInput([]byte("1,2"))
+	node, err := set.Parse("pair", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node.Subs) != 2 || input.Value(node.Subs[0]) != "1" || input.Value(node.Subs[1]) != "2" {
+		t.Fatal("bad tree")
+	}
+
+	// no values
+	set.Add("num", set.Regex(`[0-9]+`))
+	node, err = set.Parse("pair", NewInput([]byte("1,2")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !node.Equal(&Node{"pair", true, "", 0, 3, nil}) {
+		t.Fatal("bad tree")
+	}
+}
@@ -0,0 +1,154 @@
+package paza
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// bytes requested from the reader at a time
+const readChunk = 4096
+
+// NewReaderInput returns an Input reading text from reader as terminals need it.
+// Text then holds only the buffered part of the input, offsets stay absolute.
+// Text no backtrack point can reach is discarded when more text is read:
+// text before the start of the latest top level Call, and text a sequence or repetition has passed,
+// unless an enclosing choice, predicate, action, Recover, Operators or growing left recursive rule may go back to it.
+// So top level calls on a reader input must not go back before a previous one,
+// and custom parsers must not go back before positions they passed to Call.
+func NewReaderInput(reader io.Reader) *Input {
+	return &Input{
+		reader: reader,
+	}
+}
+
+// fill reads until the text up to end is buffered, reports whether it is available.
+func (i *Input) fill(end int) bool {
+	for i.reader != nil && end > i.base+len(i.Text) {
+		if i.readErr != nil {
+			break
+		}
+		i.compact()
+		if cap(i.Text)-len(i.Text) < readChunk {
+			text := make([]byte, len(i.Text), 2*cap(i.Text)+readChunk)
+			copy(text, i.Text)
+			i.Text = text
+		}
+		n, err := i.reader.Read(i.Text[len(i.Text):cap(i.Text)])
+		i.Text = i.Text[:len(i.Text)+n]
+		if err != nil {
+			i.readErr = err
+			if err != io.EOF {
+				i.abort(err)
+			}
+		}
+	}
+	return end <= i.base+len(i.Text)
+}
+
+// examine records text before end as examined by the current call
+func (i *Input) examine(end int) {
+	if end > i.reach {
+		i.reach = end
+	}
+}
+
+// atEnd reports whether there is no byte at pos
+func (i *Input) atEnd(pos int) bool {
+	i.examine(pos + 1)
+	return !i.fill(pos + 1)
+}
+
+// byteAt returns the byte at pos, pos must not be at end
+func (i *Input) byteAt(pos int) byte {
+	return i.Text[pos-i.base]
+}
+
+// rest returns the buffered text from pos, with at least n bytes if available
+func (i *Input) rest(pos int, n int) []byte {
+	if i.fill(pos + n) {
+		i.examine(pos + n)
+	} else {
+		// the end of text is examined too
+		i.examine(i.base + len(i.Text) + 1)
+	}
+	return i.Text[pos-i.base:]
+}
+
+// Slice returns the text between start and end.
+// It panics if the range is not available, e.g. already discarded from a reader input.
+func (i *Input) Slice(start, end int) []byte {
+	if start < i.base || !i.fill(end) {
+		panic(fmt.Sprintf("text not available: %d-%d", start, end))
+	}
+	return i.Text[start-i.base : end-i.base]
+}
+
+// release marks text before start as no longer needed.
+// It is called before a top level Call on reader inputs, after a cut, and as sequences progress.
+func (i *Input) release(start int) {
+	if start <= i.keep {
+		return
+	}
+	i.keep = start
+	// entries before start can not be called again
+	stack := i.stack[:0]
+	for _, entry := range i.stack {
+		if entry.active || entry.start >= start {
+			stack = append(stack, entry)
+		}
+	}
+	for j := len(stack); j < len(i.stack); j++ {
+		i.stack[j] = stackEntry{}
+	}
+	i.stack = stack
+	for key := range i.memo {
+		if key.start < start {
+			delete(i.memo, key)
+		}
+	}
+}
+
+// progress releases text of reader inputs before pos that no backtrack point can reach.
+// Combinators that may go back before pos after progress hold a mark there.
+func (i *Input) progress(pos int) {
+	if i.reader != nil {
+		i.release(i.barrier(pos))
+	}
+}
+
+// compact discards the released prefix of the buffer once it is a large part of it
+func (i *Input) compact() {
+	n := i.keep - i.base
+	if n <= 0 || n < len(i.Text)/2 {
+		return
+	}
+	if n > len(i.Text) {
+		n = len(i.Text)
+	}
+	discarded := i.Text[:n]
+	// keep position info of the discarded text
+	if lines := bytes.Count(discarded, []byte("\n")); lines > 0 {
+		i.lines += lines
+		i.column = utf8.RuneCount(discarded[bytes.LastIndexByte(discarded, '\n')+1:])
+	} else {
+		i.column += utf8.RuneCount(discarded)
+	}
+	i.Text = i.Text[:copy(i.Text, i.Text[n:])]
+	i.base += n
+}
+
+type readerRunes struct {
+	input *Input
+	pos   int
+}
+
+func (r *readerRunes) ReadRune() (rune, int, error) {
+	if r.input.atEnd(r.pos) {
+		return 0, 0, io.EOF
+	}
+	ru, l := utf8.DecodeRune(r.input.rest(r.pos, utf8.UTFMax))
+	r.pos += l
+	return ru, l, nil
+}
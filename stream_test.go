@@ -0,0 +1,144 @@
+package paza
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaderInput(t *testing.T) {
+	set := calcSet()
+	set.Add("words", set.Concat(
+		set.Literal("foo"),
+		set.OneOrMore(set.OrdChoice(set.Rune('白'), set.AnyRune())),
+	))
+	cases := []struct {
+		name string
+		text string
+	}{
+		{"expr", "1"},
+		{"expr", "1+2*3-4/5"},
+		{"expr", "(1+2)*(3-(4/5))"},
+		{"expr", "((1))+(2*(3))-4"},
+		{"expr", "(1+2"},
+		{"expr", ""},
+		{"words", "foo白a白"},
+		{"words", "fo"},
+	}
+	for _, c := range cases {
+		ok, l, node := set.Call(c.name, NewInput([]byte(c.text)), 0)
+		input := NewReaderInput(iotest.OneByteReader(strings.NewReader(c.text)))
+		readerOk, readerL, readerNode := set.Call(c.name, input, 0)
+		if ok != readerOk || l != readerL {
+			t.Fatalf("%q: got %v %d", c.text, readerOk, readerL)
+		}
+		if ok && !node.Equal(readerNode) {
+			t.Fatalf("%q: tree not match", c.text)
+		}
+	}
+}
+
+func TestReaderInputDiscard(t *testing.T) {
+	set := calcSet()
+	set.Add("line", set.Concat("expr", set.Rune('\n')))
+	buf := new(bytes.Buffer)
+	for i := 0; i < 10000; i++ {
+		buf.WriteString("(1+2)*3-4\n")
+	}
+	input := NewReaderInput(buf)
+	input.EnableMemo(-1)
+	start := 0
+	for i := 0; i < 10000; i++ {
+		ok, l, _ := set.Call("line", input, start)
+		if !ok || l != 10 {
+			t.Fatalf("line %d", i)
+		}
+		start += l
+		if len(input.Text) > 4*readChunk {
+			t.Fatalf("buffer not bounded: %d", len(input.Text))
+		}
+		if len(input.memo) > 100 {
+			t.Fatalf("memo not released: %d", len(input.memo))
+		}
+	}
+	if !input.atEnd(start) {
+		t.Fatal("should be at end")
+	}
+	if pos := input.Position(start - 1); pos != (Position{10000, 10}) {
+		t.Fatalf("got %v", pos)
+	}
+	func() {
+		defer func() {
+			if p := recover(); p == nil {
+				t.Fatal("should panic")
+			}
+		}()
+		input.Slice(0, 1)
+	}()
+}
+
+func TestReaderInputParse(t *testing.T) {
+	set := calcSet()
+	node, err := set.Parse("expr", NewReaderInput(strings.NewReader("(1+2)*3")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Len != 7 {
+		t.Fatalf("got %d", node.Len)
+	}
+
+	input := NewReaderInput(strings.NewReader("1+2\n3"))
+	_, err = set.Parse("expr", input)
+	buf := new(bytes.Buffer)
+	err.(*ParseError).Report(buf, input)
+	if buf.String() != "1:4: expected '*', '/', '+', '-' or end of input (in expr > plus-expr > term > mul-expr > mul-op)\n1+2\n   ^\n" {
+		t.Fatalf("got %s", buf.String())
+	}
+
+	readErr := errors.New("foo")
+	_, err = set.Parse("expr", NewReaderInput(iotest.OneByteReader(
+		&errReader{strings.NewReader("1+2"), readErr},
+	)))
+	if err != readErr {
+		t.Fatalf("got %v", err)
+	}
+}
+
+type errReader struct {
+	*strings.Reader
+	err error
+}
+
+func (r *errReader) Read(buf []byte) (int, error) {
+	n, err := r.Reader.Read(buf)
+	if err != nil {
+		err = r.err
+	}
+	return n, err
+}
+
+func TestReaderInputProgress(t *testing.T) {
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(`
+file <- stmt*
+stmt <- ident ' ' value ';' / ident ';'
+ident <- [a-z]+
+value <- [0-9]+ / ident
+`)); err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Repeat("foo 42;bar;baz qux;", 3000)
+	input := NewReaderInput(strings.NewReader(text))
+	node, err := set.Parse("file", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Len != len(text) || len(node.Subs) != 9000 {
+		t.Fatal("bad node")
+	}
+	if cap(input.Text) > 4*readChunk {
+		t.Fatalf("buffer not bounded: %d", cap(input.Text))
+	}
+}
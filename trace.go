@@ -0,0 +1,80 @@
+package paza
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TraceEvent describes a step of Set.Call.
+// Depth is the number of enclosing rule calls, Iteration the left recursion growth iteration.
+type TraceEvent struct {
+	Rule      string
+	Start     int
+	Depth     int
+	Ok        bool
+	Len       int
+	Memo      bool
+	Seed      bool
+	Iteration int
+}
+
+// Tracer receives the rule calls of a parse.
+// Enter and Exit are called in pairs, Exit has the result,
+// with Memo set if it came from the memo table and Seed if from the left recursion stack.
+// SeedGrow is called when a left recursive call grows its seed, with the new result.
+type Tracer interface {
+	Enter(e TraceEvent)
+	Exit(e TraceEvent)
+	SeedGrow(e TraceEvent)
+}
+
+// WriterTracer writes an indented trace like Node.Dump.
+// Calls of anonymous parsers are omitted unless Anonymous is set.
+type WriterTracer struct {
+	Writer    io.Writer
+	Anonymous bool
+	level     int
+}
+
+func NewWriterTracer(w io.Writer) *WriterTracer {
+	return &WriterTracer{
+		Writer: w,
+	}
+}
+
+func (t *WriterTracer) skip(e TraceEvent) bool {
+	return !t.Anonymous && isAnonymous(e.Rule)
+}
+
+func (t *WriterTracer) Enter(e TraceEvent) {
+	if t.skip(e) {
+		return
+	}
+	fmt.Fprintf(t.Writer, "%s%s %d\n", strings.Repeat("  ", t.level), e.Rule, e.Start)
+	t.level++
+}
+
+func (t *WriterTracer) Exit(e TraceEvent) {
+	if t.skip(e) {
+		return
+	}
+	t.level--
+	result := "fail"
+	if e.Ok {
+		result = fmt.Sprintf("ok %d-%d", e.Start, e.Start+e.Len)
+	}
+	if e.Memo {
+		result += " (memo)"
+	} else if e.Seed {
+		result += " (seed)"
+	}
+	fmt.Fprintf(t.Writer, "%s%s %d %s\n", strings.Repeat("  ", t.level), e.Rule, e.Start, result)
+}
+
+func (t *WriterTracer) SeedGrow(e TraceEvent) {
+	if t.skip(e) {
+		return
+	}
+	fmt.Fprintf(t.Writer, "%s%s %d grow %d: %d-%d\n", strings.Repeat("  ", t.level), e.Rule, e.Start, e.Iteration, e.Start, e.Start+e.Len)
+}
@@ -0,0 +1,112 @@
+package paza
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	set := NewSet()
+	set.Add("expr", set.OrdChoice(
+		set.NamedConcat("plus-expr", "expr", set.Rune('+'), "num"),
+		"num",
+	))
+	set.NamedRegex("num", `[0-9]`)
+	buf := new(bytes.Buffer)
+	input := NewInput([]byte("1+2"))
+	input.Tracer = NewWriterTracer(buf)
+	set.Call("expr", input, 0)
+	if buf.String() != `expr 0
+  plus-expr 0
+    expr 0
+    expr 0 fail (seed)
+  plus-expr 0 fail
+  num 0
+  num 0 ok 0-1
+  expr 0 grow 1: 0-1
+  plus-expr 0
+    expr 0
+    expr 0 ok 0-1 (seed)
+    num 2
+    num 2 ok 2-3
+  plus-expr 0 ok 0-3
+  expr 0 grow 2: 0-3
+  plus-expr 0
+    expr 0
+    expr 0 ok 0-3 (seed)
+  plus-expr 0 fail
+  num 0
+  num 0 ok 0-1
+expr 0 ok 0-3
+` {
+		t.Fatalf("got\n%s", buf.String())
+	}
+
+	buf.Reset()
+	input = NewInput([]byte("1"))
+	input.EnableMemo(-1)
+	input.Tracer = NewWriterTracer(buf)
+	set.Add("two", set.OrdChoice(set.Concat("num", set.Rune('+')), "num"))
+	set.Call("two", input, 0)
+	if buf.String() != `two 0
+  num 0
+  num 0 ok 0-1
+  num 0
+  num 0 ok 0-1 (memo)
+two 0 ok 0-1
+` {
+		t.Fatalf("got\n%s", buf.String())
+	}
+}
+
+type recordTracer struct {
+	events []string
+	depths []int
+}
+
+func (r *recordTracer) Enter(e TraceEvent) {
+	r.events = append(r.events, "enter "+e.Rule)
+	r.depths = append(r.depths, e.Depth)
+}
+
+func (r *recordTracer) Exit(e TraceEvent) {
+	r.events = append(r.events, "exit "+e.Rule)
+	r.depths = append(r.depths, e.Depth)
+}
+
+func (r *recordTracer) SeedGrow(e TraceEvent) {
+	r.events = append(r.events, "grow "+e.Rule)
+	r.depths = append(r.depths, e.Depth)
+}
+
+func TestTracer(t *testing.T) {
+	set := calcSet()
+	tracer := new(recordTracer)
+	input := NewInput([]byte("(1+2)*3"))
+	input.Tracer = tracer
+	set.Call("expr", input, 0)
+	// enters and exits are paired with the same depth
+	var stack []int
+	for i, event := range tracer.events {
+		switch event[:4] {
+		case "ente":
+			if tracer.depths[i] != len(stack) {
+				t.Fatalf("%d: bad depth %d", i, tracer.depths[i])
+			}
+			stack = append(stack, i)
+		case "exit":
+			enter := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if tracer.events[enter][6:] != event[5:] || tracer.depths[enter] != tracer.depths[i] {
+				t.Fatalf("%d: not paired", i)
+			}
+		case "grow":
+			if tracer.events[stack[len(stack)-1]][6:] != event[5:] {
+				t.Fatalf("%d: bad grow", i)
+			}
+		}
+	}
+	if len(stack) != 0 {
+		t.Fatal("not exited")
+	}
+}
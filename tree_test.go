@@ -51,59 +51,59 @@ func TestParseTree(t *testing.T) {
 	))
 
 	cases := []treeTestCase{
-		{"1", "expr", &Node{"expr", 0, 1, []*Node{
-			{"term", 0, 1, []*Node{
-				{"factor", 0, 1, []*Node{
-					{"digit", 0, 1, nil},
+		{"1", "expr", &Node{"expr", true, "", 0, 1, []*Node{
+			{"term", true, "", 0, 1, []*Node{
+				{"factor", true, "", 0, 1, []*Node{
+					{"digit", true, "", 0, 1, nil},
 				}}}}}}},
-		{"1+2", "expr", &Node{"expr", 0, 3, []*Node{
-			{"plus-expr", 0, 3, []*Node{
-				{"expr", 0, 1, []*Node{
-					{"term", 0, 1, []*Node{
-						{"factor", 0, 1, []*Node{
-							{"digit", 0, 1, nil}}}}}}},
-				{"plus-op", 1, 1, nil},
-				{"term", 2, 1, []*Node{
-					{"factor", 2, 1, []*Node{
-						{"digit", 2, 1, nil}}}}}}}}}},
-		{"1-2", "expr", &Node{"expr", 0, 3, []*Node{
-			{"minus-expr", 0, 3, []*Node{
-				{"expr", 0, 1, []*Node{
-					{"term", 0, 1, []*Node{
-						{"factor", 0, 1, []*Node{
-							{"digit", 0, 1, nil}}}}}}},
-				{"minus-op", 1, 1, nil},
-				{"term", 2, 1, []*Node{
-					{"factor", 2, 1, []*Node{
-						{"digit", 2, 1, nil}}}}}}}}}},
-		{"1*2", "expr", &Node{"expr", 0, 3, []*Node{
-			{"term", 0, 3, []*Node{
-				{"mul-expr", 0, 3, []*Node{
-					{"term", 0, 1, []*Node{
-						{"factor", 0, 1, []*Node{
-							{"digit", 0, 1, nil}}}}},
-					{"mul-op", 1, 1, nil},
-					{"factor", 2, 1, []*Node{
-						{"digit", 2, 1, nil}}}}}}}}}},
-		{"1/2", "expr", &Node{"expr", 0, 3, []*Node{
-			{"term", 0, 3, []*Node{
-				{"div-expr", 0, 3, []*Node{
-					{"term", 0, 1, []*Node{
-						{"factor", 0, 1, []*Node{
-							{"digit", 0, 1, nil}}}}},
-					{"div-op", 1, 1, nil},
-					{"factor", 2, 1, []*Node{
-						{"digit", 2, 1, nil}}}}}}}}}},
-		{"(1)", "expr", &Node{"expr", 0, 3, []*Node{
-			{"term", 0, 3, []*Node{
-				{"factor", 0, 3, []*Node{
-					{"quoted", 0, 3, []*Node{
-						{"left-quote", 0, 1, nil},
-						{"expr", 1, 1, []*Node{
-							{"term", 1, 1, []*Node{
-								{"factor", 1, 1, []*Node{
-									{"digit", 1, 1, nil}}}}}}},
-						{"right-quote", 2, 1, nil}}}}}}}}}},
+		{"1+2", "expr", &Node{"expr", true, "", 0, 3, []*Node{
+			{"plus-expr", true, "", 0, 3, []*Node{
+				{"expr", true, "", 0, 1, []*Node{
+					{"term", true, "", 0, 1, []*Node{
+						{"factor", true, "", 0, 1, []*Node{
+							{"digit", true, "", 0, 1, nil}}}}}}},
+				{"plus-op", true, "", 1, 1, nil},
+				{"term", true, "", 2, 1, []*Node{
+					{"factor", true, "", 2, 1, []*Node{
+						{"digit", true, "", 2, 1, nil}}}}}}}}}},
+		{"1-2", "expr", &Node{"expr", true, "", 0, 3, []*Node{
+			{"minus-expr", true, "", 0, 3, []*Node{
+				{"expr", true, "", 0, 1, []*Node{
+					{"term", true, "", 0, 1, []*Node{
+						{"factor", true, "", 0, 1, []*Node{
+							{"digit", true, "", 0, 1, nil}}}}}}},
+				{"minus-op", true, "", 1, 1, nil},
+				{"term", true, "", 2, 1, []*Node{
+					{"factor", true, "", 2, 1, []*Node{
+						{"digit", true, "", 2, 1, nil}}}}}}}}}},
+		{"1*2", "expr", &Node{"expr", true, "", 0, 3, []*Node{
+			{"term", true, "", 0, 3, []*Node{
+				{"mul-expr", true, "", 0, 3, []*Node{
+					{"term", true, "", 0, 1, []*Node{
+						{"factor", true, "", 0, 1, []*Node{
+							{"digit", true, "", 0, 1, nil}}}}},
+					{"mul-op", true, "", 1, 1, nil},
+					{"factor", true, "", 2, 1, []*Node{
+						{"digit", true, "", 2, 1, nil}}}}}}}}}},
+		{"1/2", "expr", &Node{"expr", true, "", 0, 3, []*Node{
+			{"term", true, "", 0, 3, []*Node{
+				{"div-expr", true, "", 0, 3, []*Node{
+					{"term", true, "", 0, 1, []*Node{
+						{"factor", true, "", 0, 1, []*Node{
+							{"digit", true, "", 0, 1, nil}}}}},
+					{"div-op", true, "", 1, 1, nil},
+					{"factor", true, "", 2, 1, []*Node{
+						{"digit", true, "", 2, 1, nil}}}}}}}}}},
+		{"(1)", "expr", &Node{"expr", true, "", 0, 3, []*Node{
+			{"term", true, "", 0, 3, []*Node{
+				{"factor", true, "", 0, 3, []*Node{
+					{"quoted", true, "", 0, 3, []*Node{
+						{"left-quote", true, "", 0, 1, nil},
+						{"expr", true, "", 1, 1, []*Node{
+							{"term", true, "", 1, 1, []*Node{
+								{"factor", true, "", 1, 1, []*Node{
+									{"digit", true, "", 1, 1, nil}}}}}}},
+						{"right-quote", true, "", 2, 1, nil}}}}}}}}}},
 	}
 	testTree(t, set, cases)
 }
@@ -121,34 +121,60 @@ func TestParseTree2(t *testing.T) {
 		set.NamedZeroOrMore("stars", set.NamedRune("star", '*')), // must be last
 	))
 	cases := []treeTestCase{
-		{"1", "foo", &Node{"foo", 0, 1, []*Node{
-			{"digit", 0, 1, nil}}}},
-		{"z", "foo", &Node{"foo", 0, 1, []*Node{
-			{"alpha", 0, 1, nil}}}},
-		{"!", "foo", &Node{"foo", 0, 1, []*Node{
-			{"punct", 0, 1, []*Node{
-				{"!", 0, 1, nil}}}}}},
-		{"-", "foo", &Node{"foo", 0, 1, []*Node{
-			{"dashes", 0, 1, []*Node{
-				{"dash", 0, 1, nil},
+		{"1", "foo", &Node{"foo", true, "", 0, 1, []*Node{
+			{"digit", true, "", 0, 1, nil}}}},
+		{"z", "foo", &Node{"foo", true, "", 0, 1, []*Node{
+			{"alpha", true, "", 0, 1, nil}}}},
+		{"!", "foo", &Node{"foo", true, "", 0, 1, []*Node{
+			{"punct", true, "", 0, 1, []*Node{
+				{"!", true, "", 0, 1, nil}}}}}},
+		{"-", "foo", &Node{"foo", true, "", 0, 1, []*Node{
+			{"dashes", true, "", 0, 1, []*Node{
+				{"dash", true, "", 0, 1, nil},
 			}}}}},
-		{"--", "foo", &Node{"foo", 0, 2, []*Node{
-			{"dashes", 0, 2, []*Node{
-				{"dash", 0, 1, nil},
-				{"dash", 1, 1, nil},
+		{"--", "foo", &Node{"foo", true, "", 0, 2, []*Node{
+			{"dashes", true, "", 0, 2, []*Node{
+				{"dash", true, "", 0, 1, nil},
+				{"dash", true, "", 1, 1, nil},
 			}}}}},
-		{"---", "foo", &Node{"foo", 0, 3, []*Node{
-			{"dashes", 0, 3, []*Node{
-				{"dash", 0, 1, nil},
-				{"dash", 1, 1, nil},
-				{"dash", 2, 1, nil},
+		{"---", "foo", &Node{"foo", true, "", 0, 3, []*Node{
+			{"dashes", true, "", 0, 3, []*Node{
+				{"dash", true, "", 0, 1, nil},
+				{"dash", true, "", 1, 1, nil},
+				{"dash", true, "", 2, 1, nil},
 			}}}}},
-		{"*", "foo", &Node{"foo", 0, 1, []*Node{
-			{"stars", 0, 1, []*Node{
-				{"star", 0, 1, nil}}}}}},
-		{"#", "foo", &Node{"foo", 0, 1, []*Node{
-			{"sharps", 0, 1, []*Node{
-				{"sharp", 0, 1, nil}}}}}},
+		{"*", "foo", &Node{"foo", true, "", 0, 1, []*Node{
+			{"stars", true, "", 0, 1, []*Node{
+				{"star", true, "", 0, 1, nil}}}}}},
+		{"#", "foo", &Node{"foo", true, "", 0, 1, []*Node{
+			{"sharps", true, "", 0, 1, []*Node{
+				{"sharp", true, "", 0, 1, nil}}}}}},
 	}
 	testTree(t, set, cases)
 }
+
+func TestAnonymousNodeNames(t *testing.T) {
+	for _, other := range []bool{false, true} {
+		set := NewSet()
+		if other {
+			// paths do not depend on other rules
+			set.Add("other", set.Concat(set.Rune('x'), set.ZeroOrMore(set.Rune('y'))))
+		}
+		set.Add("list", set.Concat(
+			set.NamedRegex("item", `[a-z]+`),
+			set.ZeroOrMore(set.Concat(set.Rune(','), "item")),
+		))
+		cases := []treeTestCase{
+			{"a,b", "list", &Node{"list", true, "", 0, 3, []*Node{
+				{"item", true, "", 0, 1, nil},
+				{"repeat", false, "list/1", 1, 2, []*Node{
+					{"concat", false, "list/1/0", 1, 2, []*Node{
+						{"rune", false, "list/1/0/0", 1, 1, nil},
+						{"item", true, "", 2, 1, nil},
+					}},
+				}},
+			}}},
+		}
+		testTree(t, set, cases)
+	}
+}
@@ -0,0 +1,228 @@
+package paza
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+Decoder fills Go values from parse trees.
+
+Struct fields are tagged with rule names, alternatives separated by '|':
+
+	type Binary struct {
+		Left  Expr   `paza:"expr|term"`
+		Op    string `paza:"plus-op|minus-op"`
+		Right Expr   `paza:"term"`
+		Note  *Note  `paza:"note"`
+		Args  []Arg  `paza:"arg"`
+		Doc   string `paza:"doc,optional"`
+	}
+
+A field matches sub nodes with one of the names, searched through anonymous nodes only.
+Strings, byte slices, numbers and bools are decoded from the matched text,
+structs recursively, pointers are nil if nothing matches, slices take all matches,
+and *Node fields take the node itself.
+Interface fields take a value of the type registered for the matched node,
+or for its first descendant having a registered type, which suits ordered choices.
+Other fields must match exactly one node unless tagged optional.
+*/
+type Decoder struct {
+	input *Input
+	types map[string]reflect.Type
+}
+
+type UnmarshalError struct {
+	Path   string
+	Rule   string
+	Offset int
+	Msg    string
+}
+
+func (e *UnmarshalError) Error() string {
+	return fmt.Sprintf("%s: rule %s at offset %d: %s", e.Path, e.Rule, e.Offset, e.Msg)
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil))
+
+func NewDecoder(input *Input) *Decoder {
+	return &Decoder{
+		input: input,
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// Register sets the type decoded from nodes of the rule into interface values.
+func (d *Decoder) Register(rule string, v interface{}) {
+	d.types[rule] = reflect.TypeOf(v)
+}
+
+// Decode decodes the node into v, which must be a non-nil pointer.
+func (d *Decoder) Decode(node *Node, v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("paza: decode into non-pointer %T", v)
+	}
+	return d.decode(node, ptr.Elem(), ptr.Elem().Type().String())
+}
+
+// Unmarshal decodes the node into v with a Decoder without registered types.
+func Unmarshal(input *Input, node *Node, v interface{}) error {
+	return NewDecoder(input).Decode(node, v)
+}
+
+func (d *Decoder) errorf(path string, node *Node, format string, args ...interface{}) error {
+	return &UnmarshalError{
+		Path:   path,
+		Rule:   node.Name,
+		Offset: node.Start,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (d *Decoder) decode(node *Node, v reflect.Value, path string) error {
+	if v.Type() == nodeType {
+		v.Set(reflect.ValueOf(node))
+		return nil
+	}
+	text := string(d.input.Slice(node.Start, node.Start+node.Len))
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return d.errorf(path, node, "%v", err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return d.errorf(path, node, "%v", err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return d.errorf(path, node, "%v", err)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return d.errorf(path, node, "%v", err)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return d.errorf(path, node, "cannot decode into %s", v.Type())
+		}
+		v.SetBytes([]byte(text))
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := d.decode(node, elem.Elem(), path); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Interface:
+		return d.decodeInterface(node, v, path)
+	case reflect.Struct:
+		return d.decodeStruct(node, v, path)
+	default:
+		return d.errorf(path, node, "cannot decode into %s", v.Type())
+	}
+	return nil
+}
+
+func (d *Decoder) decodeInterface(node *Node, v reflect.Value, path string) error {
+	var find func(node *Node) (*Node, reflect.Type)
+	find = func(node *Node) (*Node, reflect.Type) {
+		if node == nil { // predicate
+			return nil, nil
+		}
+		if t, ok := d.types[node.Name]; ok {
+			return node, t
+		}
+		for _, sub := range node.Subs {
+			if n, t := find(sub); n != nil {
+				return n, t
+			}
+		}
+		return nil, nil
+	}
+	match, t := find(node)
+	if match == nil {
+		return d.errorf(path, node, "no registered type for %s", v.Type())
+	}
+	if !t.AssignableTo(v.Type()) {
+		return d.errorf(path, match, "registered type %s is not assignable to %s", t, v.Type())
+	}
+	elem := reflect.New(t).Elem()
+	if err := d.decode(match, elem, path); err != nil {
+		return err
+	}
+	v.Set(elem)
+	return nil
+}
+
+func (d *Decoder) decodeStruct(node *Node, v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("paza")
+		if !ok || field.PkgPath != "" { // untagged or unexported
+			continue
+		}
+		optional := false
+		if i := strings.Index(tag, ","); i >= 0 {
+			optional = tag[i+1:] == "optional"
+			tag = tag[:i]
+		}
+		names := make(map[string]bool)
+		for _, name := range strings.Split(tag, "|") {
+			names[name] = true
+		}
+		matches := findNamed(node, names, nil)
+		fieldPath := path + "." + field.Name
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(matches), len(matches))
+			for j, match := range matches {
+				if err := d.decode(match, slice.Index(j), fmt.Sprintf("%s[%d]", fieldPath, j)); err != nil {
+					return err
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+		switch {
+		case len(matches) == 0 && (optional || fv.Kind() == reflect.Ptr):
+			continue
+		case len(matches) == 0:
+			return d.errorf(fieldPath, node, "no sub node named %s", tag)
+		case len(matches) > 1:
+			return d.errorf(fieldPath, node, "%d sub nodes named %s", len(matches), tag)
+		}
+		if err := d.decode(matches[0], fv, fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findNamed collects sub nodes with the names, descending into anonymous nodes only
+func findNamed(node *Node, names map[string]bool, ret []*Node) []*Node {
+	for _, sub := range node.Subs {
+		if sub == nil {
+			continue
+		}
+		if names[sub.Name] {
+			ret = append(ret, sub)
+		} else if !sub.Named {
+			ret = findNamed(sub, names, ret)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,234 @@
+package paza
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testExpr interface{}
+
+type testNum int
+
+type testSum struct {
+	Left  testExpr `paza:"expr"`
+	Op    string   `paza:"plus-op|minus-op"`
+	Right testExpr `paza:"term"`
+}
+
+type testProduct struct {
+	Left  testExpr `paza:"term"`
+	Op    string   `paza:"mul-op|div-op"`
+	Right testExpr `paza:"factor"`
+}
+
+func testDecoder(input *Input) *Decoder {
+	d := NewDecoder(input)
+	d.Register("digit", testNum(0))
+	d.Register("plus-expr", &testSum{})
+	d.Register("minus-expr", &testSum{})
+	d.Register("mul-expr", &testProduct{})
+	d.Register("div-expr", &testProduct{})
+	return d
+}
+
+func TestUnmarshal(t *testing.T) {
+	set := calcSet()
+	input := NewInput([]byte("1+2*(3-4)"))
+	node, err := set.Parse("expr", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expr testExpr
+	if err := testDecoder(input).Decode(node, &expr); err != nil {
+		t.Fatal(err)
+	}
+	expected := &testSum{
+		Left: testNum(1),
+		Op:   "+",
+		Right: &testProduct{
+			Left: testNum(2),
+			Op:   "*",
+			Right: &testSum{
+				Left:  testNum(3),
+				Op:    "-",
+				Right: testNum(4),
+			},
+		},
+	}
+	if !reflect.DeepEqual(expr, expected) {
+		t.Fatalf("got %#v", expr)
+	}
+}
+
+func TestUnmarshalFields(t *testing.T) {
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(`
+decl  <- name ws '=' ws value (ws ',' ws value)* (ws flag)? (ws note)? ws ';'
+name  <- [a-z]+
+value <- int / float
+int   <- [0-9]+ !'.'
+float <- [0-9]+ '.' [0-9]+
+flag  <- 'true' / 'false'
+note  <- '#' word
+word  <- [a-z]+
+ws    <- ' '*
+`)); err != nil {
+		t.Fatal(err)
+	}
+	type Note struct {
+		Word []byte `paza:"word"`
+	}
+	type Value struct {
+		Int   *int64   `paza:"int"`
+		Float *float64 `paza:"float"`
+	}
+	type Decl struct {
+		Name   string  `paza:"name"`
+		Values []Value `paza:"value"`
+		Ints   []int16 `paza:"int"`
+		Flag   bool    `paza:"flag,optional"`
+		Note   *Note   `paza:"note"`
+		Node   *Node   `paza:"name"`
+		Other  string
+		hidden string `paza:"name"`
+	}
+
+	input := NewInput([]byte("foo = 1, 2.5, 3 true #bar;"))
+	node, err := set.Parse("decl", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decl Decl
+	if err := Unmarshal(input, node, &decl); err != nil {
+		t.Fatal(err)
+	}
+	if decl.Name != "foo" || len(decl.Values) != 3 ||
+		*decl.Values[0].Int != 1 || decl.Values[0].Float != nil ||
+		*decl.Values[1].Float != 2.5 || decl.Values[1].Int != nil ||
+		*decl.Values[2].Int != 3 ||
+		len(decl.Ints) != 0 ||
+		!decl.Flag || string(decl.Note.Word) != "bar" ||
+		decl.Node.Name != "name" || decl.Node.Len != 3 ||
+		decl.hidden != "" {
+		t.Fatalf("got %+v", decl)
+	}
+
+	input = NewInput([]byte("foo=1;"))
+	node, _ = set.Parse("decl", input)
+	decl = Decl{}
+	if err := Unmarshal(input, node, &decl); err != nil {
+		t.Fatal(err)
+	}
+	if decl.Flag || decl.Note != nil || len(decl.Values) != 1 {
+		t.Fatalf("got %+v", decl)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	set := calcSet()
+	parse := func(text string) (*Input, *Node) {
+		input := NewInput([]byte(text))
+		node, err := set.Parse("expr", input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return input, node
+	}
+
+	input, node := parse("1+2")
+	var expr testExpr
+	err := Unmarshal(input, node, &expr)
+	if err == nil || err.Error() != "paza.testExpr: rule expr at offset 0: no registered type for paza.testExpr" {
+		t.Fatalf("got %v", err)
+	}
+	if err := Unmarshal(input, node, expr); err == nil || err.Error() != "paza: decode into non-pointer <nil>" {
+		t.Fatalf("got %v", err)
+	}
+
+	type Missing struct {
+		Op string `paza:"mul-op"`
+	}
+	var missing Missing
+	err = Unmarshal(input, node.Subs[0], &missing)
+	if err == nil || err.Error() != "paza.Missing.Op: rule plus-expr at offset 0: no sub node named mul-op" {
+		t.Fatalf("got %v", err)
+	}
+
+	type Ops struct {
+		Ops string `paza:"expr|term"`
+	}
+	var ops Ops
+	err = Unmarshal(input, node.Subs[0], &ops)
+	if err == nil || err.Error() != "paza.Ops.Ops: rule plus-expr at offset 0: 2 sub nodes named expr|term" {
+		t.Fatalf("got %v", err)
+	}
+
+	type Bad struct {
+		Left struct {
+			N int8 `paza:"digit"`
+		} `paza:"expr"`
+		Right int `paza:"term"`
+	}
+	input, node = parse("1+300")
+	var bad Bad
+	err = Unmarshal(input, node.Subs[0], &bad)
+	if err == nil || err.Error() != `paza.Bad.Left.N: rule expr at offset 0: no sub node named digit` {
+		t.Fatalf("got %v", err)
+	}
+	type Bad2 struct {
+		Right struct {
+			Factor struct {
+				N int8 `paza:"digit"`
+			} `paza:"factor"`
+		} `paza:"term"`
+	}
+	var bad2 Bad2
+	err = Unmarshal(input, node.Subs[0], &bad2)
+	if err == nil || err.Error() != `paza.Bad2.Right.Factor.N: rule digit at offset 2: strconv.ParseInt: parsing "300": value out of range` {
+		t.Fatalf("got %v", err)
+	}
+
+	type Foo struct {
+		N interface{ Foo() } `paza:"plus-expr"`
+	}
+	d := NewDecoder(input)
+	d.Register("digit", "")
+	err = d.Decode(node, &Foo{})
+	if err == nil || err.Error() != "paza.Foo.N: rule digit at offset 0: registered type string is not assignable to interface { Foo() }" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestUnmarshalPredicate(t *testing.T) {
+	set := NewSet()
+	if err := set.LoadGrammar([]byte(`
+s <- &'a' x y
+x <- 'a'
+y <- [0-9]+
+`)); err != nil {
+		t.Fatal(err)
+	}
+	input := NewInput([]byte("a12"))
+	node, err := set.Parse("s", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDecoder(input)
+	d.Register("y", testNum(0))
+	var v testExpr
+	if err := d.Decode(node, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v != testNum(12) {
+		t.Fatalf("got %#v", v)
+	}
+	var s struct {
+		Y int `paza:"y"`
+	}
+	if err := Unmarshal(input, node, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Y != 12 {
+		t.Fatalf("got %#v", s)
+	}
+}
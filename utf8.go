@@ -0,0 +1,42 @@
+package paza
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// UTF8Policy decides how rune based parsers handle invalid UTF-8 input.
+type UTF8Policy int
+
+const (
+	// abort the parse with a *UTF8Error
+	InvalidUTF8Error UTF8Policy = iota
+	// fail the match
+	InvalidUTF8Fail
+	// decode as utf8.RuneError, one byte long
+	InvalidUTF8Replace
+)
+
+type UTF8Error struct {
+	Offset int
+}
+
+func (e *UTF8Error) Error() string {
+	return fmt.Sprintf("invalid UTF-8 at offset %d", e.Offset)
+}
+
+// decodeRune decodes the rune at start according to the InvalidUTF8 policy.
+// start must not be at the end of the text.
+func (i *Input) decodeRune(start int) (r rune, l int, ok bool) {
+	r, l = utf8.DecodeRune(i.rest(start, utf8.UTFMax))
+	if r != utf8.RuneError || l > 1 {
+		return r, l, true
+	}
+	switch i.InvalidUTF8 {
+	case InvalidUTF8Replace:
+		return r, l, true
+	case InvalidUTF8Error:
+		i.abort(&UTF8Error{start})
+	}
+	return r, l, false
+}
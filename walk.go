@@ -0,0 +1,104 @@
+package paza
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SkipChildren returned by a pre hook of Walk skips the sub nodes.
+var SkipChildren = errors.New("skip children")
+
+// Walk calls pre before and post after visiting the sub nodes of each node, in depth-first order.
+// pre or post may be nil. A SkipChildren error from pre skips the sub nodes, still calling post,
+// any other error stops the walk and is returned.
+func Walk(node *Node, pre, post func(node *Node) error) error {
+	if node == nil {
+		return nil
+	}
+	if pre != nil {
+		if err := pre(node); err == SkipChildren {
+			if post != nil {
+				return post(node)
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	for _, sub := range node.Subs {
+		if err := Walk(sub, pre, post); err != nil {
+			return err
+		}
+	}
+	if post != nil {
+		return post(node)
+	}
+	return nil
+}
+
+// Rewrite rebuilds the tree bottom-up without modifying it.
+// fn receives a copy of each node with rewritten Subs and returns the nodes replacing it in its parent:
+// none drops it, the node itself keeps it, its Subs splice them into the parent.
+// Returned nodes with a zero Len and Subs get the span of their Subs.
+// Replacements must lie in order within the span of the node they replace, Rewrite panics otherwise.
+// If the root is replaced by several nodes, they are returned as Subs of a copy of it.
+func Rewrite(node *Node, fn func(node *Node) []*Node) *Node {
+	nodes := rewrite(node, fn)
+	switch len(nodes) {
+	case 0:
+		return nil
+	case 1:
+		return nodes[0]
+	}
+	root := *node
+	root.Subs = nodes
+	return &root
+}
+
+func rewrite(node *Node, fn func(node *Node) []*Node) []*Node {
+	if node == nil {
+		return []*Node{nil}
+	}
+	n := *node
+	if node.Subs != nil {
+		n.Subs = make([]*Node, 0, len(node.Subs))
+		for _, sub := range node.Subs {
+			n.Subs = append(n.Subs, rewrite(sub, fn)...)
+		}
+	}
+	ret := fn(&n)
+	end := n.Start
+	for _, r := range ret {
+		if r == nil {
+			continue
+		}
+		if r.Len == 0 && len(r.Subs) > 0 {
+			span(r)
+		}
+		if r.Start < end || r.Start+r.Len > n.Start+n.Len {
+			panic(fmt.Sprintf("rewritten node %s %d-%d not in order within %s %d-%d",
+				r.Name, r.Start, r.Start+r.Len, n.Name, n.Start, n.Start+n.Len))
+		}
+		end = r.Start + r.Len
+	}
+	return ret
+}
+
+// span sets the node span to cover its sub nodes
+func span(node *Node) {
+	first, last := -1, -1
+	for i, sub := range node.Subs {
+		if sub == nil {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first < 0 {
+		return
+	}
+	node.Start = node.Subs[first].Start
+	node.Len = node.Subs[last].Start + node.Subs[last].Len - node.Start
+}
@@ -0,0 +1,114 @@
+package paza
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	set := calcSet()
+	_, _, node := set.Call("expr", NewInput([]byte("1+(2)")), 0)
+	var events []string
+	err := Walk(node, func(n *Node) error {
+		if !n.Named {
+			return nil
+		}
+		events = append(events, "<"+n.Name)
+		if n.Name == "quoted" {
+			return SkipChildren
+		}
+		return nil
+	}, func(n *Node) error {
+		if n.Named {
+			events = append(events, n.Name+">")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(events, " "); s != "<expr <plus-expr <expr <term <factor <digit digit> factor> term> expr> "+
+		"<plus-op plus-op> <term <factor <quoted quoted> factor> term> plus-expr> expr>" {
+		t.Fatalf("got %s", s)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = Walk(node, nil, func(node *Node) error {
+		n++
+		if node.Name == "plus-op" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 5 {
+		t.Fatalf("got %v %d", err, n)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	set := calcSet()
+	text := []byte("1+(2*3)")
+	_, _, node := set.Call("expr", NewInput(text), 0)
+	// collapse single child chains and drop operators and quotes
+	rewritten := Rewrite(node, func(n *Node) []*Node {
+		switch n.Name {
+		case "plus-op", "mul-op", "left-quote", "right-quote":
+			return nil
+		}
+		if len(n.Subs) == 1 {
+			return n.Subs
+		}
+		return []*Node{n}
+	})
+	expected := &Node{"plus-expr", true, "", 0, 7, []*Node{
+		{"digit", true, "", 0, 1, nil},
+		{"mul-expr", true, "", 3, 3, []*Node{
+			{"digit", true, "", 3, 1, nil},
+			{"digit", true, "", 5, 1, nil},
+		}},
+	}}
+	if !rewritten.Equal(expected) {
+		t.Fatal("bad rewrite")
+	}
+	if node.Name != "expr" || len(node.Subs) != 1 || len(node.Subs[0].Subs) != 3 {
+		t.Fatal("original tree modified")
+	}
+
+	// new wrappers get spans of their subs
+	rewritten = Rewrite(rewritten, func(n *Node) []*Node {
+		if n.Name == "digit" {
+			return []*Node{{Name: "num", Subs: []*Node{n}}}
+		}
+		return []*Node{n}
+	})
+	if num := rewritten.Subs[1].Subs[1]; num.Name != "num" || num.Start != 5 || num.Len != 1 {
+		t.Fatal("bad span")
+	}
+
+	// several nodes at root
+	root := Rewrite(expected, func(n *Node) []*Node {
+		if n.Name == "plus-expr" {
+			return n.Subs
+		}
+		return []*Node{n}
+	})
+	if root.Name != "plus-expr" || len(root.Subs) != 2 {
+		t.Fatal("bad root")
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p == nil || p.(string) != "rewritten node digit 0-1 not in order within plus-expr 0-7" {
+				t.Fatalf("got %v", p)
+			}
+		}()
+		Rewrite(expected, func(n *Node) []*Node {
+			if n.Name == "plus-expr" {
+				return []*Node{{Name: "digit", Start: 5, Len: 1}, n.Subs[0]}
+			}
+			return []*Node{n}
+		})
+	}()
+}